@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Clipboard puts text on the system clipboard.
+type Clipboard interface {
+	Copy(text string) error
+}
+
+// CLIPBOARDS are the backends --clipboard can pick. auto picks the first of
+// them that works in the current session.
+var CLIPBOARDS = []string{"auto", "wl-copy", "xclip", "xsel", "pbcopy", "osc52"}
+
+// commandClipboard pipes the text into a clipboard tool.
+type commandClipboard struct {
+	name string
+	args []string
+}
+
+// CLIPBOARD_COMMANDS are the clipboard tools and their arguments to read the
+// text from stdin.
+var CLIPBOARD_COMMANDS = map[string]commandClipboard{
+	"wl-copy": {"wl-copy", nil},
+	"xclip":   {"xclip", []string{"-selection", "clipboard"}},
+	"xsel":    {"xsel", []string{"--clipboard", "--input"}},
+	"pbcopy":  {"pbcopy", nil},
+}
+
+// Copy leaves the output of the tool alone, xclip and xsel keep running in
+// the background to serve the clipboard and would hold a pipe open.
+func (c commandClipboard) Copy(text string) error {
+	cmd := exec.Command(c.name, c.args...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", c.name, err)
+	}
+	return nil
+}
+
+// osc52Clipboard asks the terminal to set the clipboard with the OSC 52
+// escape sequence, which reaches the local clipboard over ssh as well.
+// Inside tmux the sequence is passed through to the outer terminal.
+type osc52Clipboard struct {
+	w    io.Writer
+	tmux bool
+}
+
+func (c osc52Clipboard) Copy(text string) error {
+	seq := fmt.Sprintf("\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	if c.tmux {
+		seq = "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+	}
+	_, err := io.WriteString(c.w, seq)
+	return err
+}
+
+// newClipboard returns the named backend. auto prefers OSC 52 in ssh
+// sessions, where the tools would set the clipboard of the remote machine,
+// then the tool of the display server and OSC 52 as the last resort.
+func newClipboard(name string) (Clipboard, error) {
+	if c, ok := CLIPBOARD_COMMANDS[name]; ok {
+		return c, nil
+	}
+
+	switch name {
+	case "osc52":
+		return terminalClipboard()
+	case "auto":
+	default:
+		return nil, fmt.Errorf("unknown clipboard %q, use one of %s", name, strings.Join(CLIPBOARDS, ", "))
+	}
+
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		return terminalClipboard()
+	}
+
+	var candidates []string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, "wl-copy")
+	}
+	if os.Getenv("DISPLAY") != "" {
+		candidates = append(candidates, "xclip", "xsel")
+	}
+	candidates = append(candidates, "pbcopy")
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate); err == nil {
+			return CLIPBOARD_COMMANDS[candidate], nil
+		}
+	}
+
+	return terminalClipboard()
+}
+
+// terminalClipboard writes OSC 52 to stdout or, if it is redirected, to
+// stderr.
+func terminalClipboard() (Clipboard, error) {
+	tmux := os.Getenv("TMUX") != ""
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if isTerminal(f) {
+			return osc52Clipboard{f, tmux}, nil
+		}
+	}
+	return nil, fmt.Errorf("no clipboard tool found and no terminal for OSC 52")
+}
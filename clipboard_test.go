@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fakeClipboard records what is copied.
+type fakeClipboard struct {
+	copied []string
+}
+
+func (c *fakeClipboard) Copy(text string) error {
+	c.copied = append(c.copied, text)
+	return nil
+}
+
+func TestOSC52Clipboard(t *testing.T) {
+	tests := []struct {
+		tmux     bool
+		expected string
+	}{
+		{false, "\033]52;c;IzNBN0JENQ==\a"},
+		{true, "\033Ptmux;\033\033]52;c;IzNBN0JENQ==\a\033\\"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := (osc52Clipboard{&buf, tt.tmux}).Copy("#3A7BD5"); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.expected {
+			t.Errorf("tmux %v: expected %q, but got %q", tt.tmux, tt.expected, got)
+		}
+	}
+}
+
+func TestNewClipboard(t *testing.T) {
+	for name, expected := range CLIPBOARD_COMMANDS {
+		c, err := newClipboard(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := c.(commandClipboard); !ok || got.name != expected.name {
+			t.Errorf("%s: expected %v, but got %v", name, expected, c)
+		}
+	}
+
+	if _, err := newClipboard("clippy"); err == nil {
+		t.Error("expected an error for an unknown clipboard")
+	}
+}
+
+// without --sort the top color is the one covering the most pixels, however
+// the colors were grouped
+func TestCopyTop(t *testing.T) {
+	colors := map[RGB]int{
+		{10, 10, 10}:    5,
+		{200, 0, 0}:     40,
+		{0, 0, 200}:     40,
+		{205, 0, 0}:     1,
+		{255, 255, 255}: 30,
+	}
+
+	clipboard := &fakeClipboard{}
+	for range 20 {
+		grouped, _ := groupColors(colors, 10)
+		if err := copyTop(clipboard, sort(grouped, ""), NOTATIONS["HEX"]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, text := range clipboard.copied {
+		if text != "#CA0000" {
+			t.Fatalf("expected the red group #CA0000 every time, but got %v", clipboard.copied)
+		}
+	}
+
+	if err := copyTop(clipboard, nil, NOTATIONS["HEX"]); err != nil || len(clipboard.copied) != 20 {
+		t.Errorf("expected nothing copied without colors, but got %v and %v", clipboard.copied, err)
+	}
+}
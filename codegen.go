@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NAMINGS are the ways code exporters name the variable of a color: by its
+// position in the palette or by its hex code.
+var NAMINGS = []string{"index", "hex"}
+
+// variableNames returns the kebab-case name of every color, made unique by
+// appending a counter to repeated names.
+func variableNames(e Export) ([]string, error) {
+	prefix := strings.Join(words(e.Prefix), "-")
+	if prefix == "" {
+		return nil, fmt.Errorf("the export prefix needs at least one letter or digit")
+	}
+
+	names := make([]string, 0, len(e.Colors))
+	seen := map[string]int{}
+	for i, cc := range e.Colors {
+		var suffix string
+		switch e.Naming {
+		case "index":
+			suffix = fmt.Sprint(i + 1)
+		case "hex":
+			suffix = fmt.Sprintf("%02x%02x%02x", cc.rgb.red, cc.rgb.green, cc.rgb.blue)
+		default:
+			return nil, fmt.Errorf("unknown naming %q, use one of %s", e.Naming, strings.Join(NAMINGS, ", "))
+		}
+
+		name := prefix + "-" + suffix
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, seen[name])
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// words splits s at everything that is not a letter or a digit.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// writeVariables writes one "<sigil><name>: <hex>;" line per color, indented
+// by indent.
+func writeVariables(w io.Writer, e Export, sigil, indent string) error {
+	names, err := variableNames(e)
+	if err != nil {
+		return err
+	}
+
+	for i, cc := range e.Colors {
+		if _, err := fmt.Fprintf(w, "%s%s%s: %s;\n", indent, sigil, names[i], lowerHex(cc.rgb)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSS writes the colors as custom properties of :root.
+func writeCSS(w io.Writer, e Export) error {
+	fmt.Fprintf(w, "/* %s */\n:root {\n", e.Name)
+	if err := writeVariables(w, e, "--", "  "); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+func writeSCSS(w io.Writer, e Export) error {
+	fmt.Fprintf(w, "// %s\n", e.Name)
+	return writeVariables(w, e, "$", "")
+}
+
+func writeLess(w io.Writer, e Export) error {
+	fmt.Fprintf(w, "// %s\n", e.Name)
+	return writeVariables(w, e, "@", "")
+}
+
+// tailwindColors renders the theme.colors object of a Tailwind config with
+// the keys in palette order.
+func tailwindColors(e Export, indent string) (string, error) {
+	names, err := variableNames(e)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for i, cc := range e.Colors {
+		key, _ := json.Marshal(names[i])
+		fmt.Fprintf(&b, "%s  %s: \"%s\"", indent, key, lowerHex(cc.rgb))
+		if i < len(e.Colors)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "}")
+
+	return b.String(), nil
+}
+
+// writeTailwindJS writes a module to be required from tailwind.config.js or
+// used as a preset.
+func writeTailwindJS(w io.Writer, e Export) error {
+	colors, err := tailwindColors(e, "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "// %s\nmodule.exports = {\n  theme: {\n    colors: %s,\n  },\n};\n", e.Name, colors)
+	return err
+}
+
+func writeTailwindJSON(w io.Writer, e Export) error {
+	colors, err := tailwindColors(e, "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "{\n  \"theme\": {\n    \"colors\": %s\n  }\n}\n", colors)
+	return err
+}
+
+// goName turns a kebab-case name into an exported Go identifier.
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "-") {
+		if word == "" {
+			continue
+		}
+		first, size := utf8.DecodeRuneInString(word)
+		if b.Len() == 0 && unicode.IsDigit(first) {
+			b.WriteString("C")
+		}
+		b.WriteString(string(unicode.ToUpper(first)) + word[size:])
+	}
+	return b.String()
+}
+
+// goNames converts the names with goName and appends a counter to any
+// identifier that is already taken, as different names such as a-1-b and
+// a1-b can give the same one.
+func goNames(names []string) []string {
+	idents := make([]string, 0, len(names))
+	taken := map[string]bool{}
+	for _, name := range names {
+		ident := goName(name)
+		for n := 2; taken[ident]; n++ {
+			ident = fmt.Sprintf("%s%d", goName(name), n)
+		}
+		taken[ident] = true
+		idents = append(idents, ident)
+	}
+	return idents
+}
+
+// writeGo writes a Go file of package palette with a color.RGBA variable
+// per color.
+func writeGo(w io.Writer, e Export) error {
+	names, err := variableNames(e)
+	if err != nil {
+		return err
+	}
+	idents := goNames(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by color-picker from the colors of %s. DO NOT EDIT.\n\n", e.Name)
+	b.WriteString("package palette\n\nimport \"image/color\"\n\nvar (\n")
+	for i, cc := range e.Colors {
+		fmt.Fprintf(&b, "%s = color.RGBA{R: 0x%02x, G: 0x%02x, B: 0x%02x, A: 0xff}\n",
+			idents[i], cc.rgb.red, cc.rgb.green, cc.rgb.blue)
+	}
+	b.WriteString(")\n")
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVariableNames(t *testing.T) {
+	colors := RGBColorPairSlice{
+		{rgb: RGB{0x3a, 0x7b, 0xd5}},
+		{rgb: RGB{255, 255, 255}},
+		{rgb: RGB{0x3a, 0x7b, 0xd5}},
+	}
+
+	tests := []struct {
+		prefix   string
+		naming   string
+		expected []string
+	}{
+		{"color", "index", []string{"color-1", "color-2", "color-3"}},
+		{"Brand Colors!", "hex", []string{"brand-colors-3a7bd5", "brand-colors-ffffff", "brand-colors-3a7bd5-2"}},
+	}
+
+	for _, tt := range tests {
+		got, err := variableNames(Export{Prefix: tt.prefix, Naming: tt.naming, Colors: colors})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("%s by %s: expected %v, but got %v", tt.prefix, tt.naming, tt.expected, got)
+		}
+	}
+
+	if _, err := variableNames(Export{Prefix: "--", Naming: "index", Colors: colors}); err == nil {
+		t.Error("expected an error for a prefix without letters")
+	}
+	if _, err := variableNames(Export{Prefix: "color", Naming: "name", Colors: colors}); err == nil {
+		t.Error("expected an error for an unknown naming")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"color-1":             "Color1",
+		"brand-colors-3a7bd5": "BrandColors3a7bd5",
+		"1st-3a7bd5":          "C1st3a7bd5",
+		"ärger-2":             "Ärger2",
+	}
+	for name, expected := range tests {
+		if got := goName(name); got != expected {
+			t.Errorf("%s: expected %s, but got %s", name, expected, got)
+		}
+	}
+}
+
+func TestGoNames(t *testing.T) {
+	names := []string{"a-1-b", "a1-b", "a-1-b-2", "a1b2"}
+	expected := []string{"A1B", "A1B2", "A1B22", "A1b2"}
+	if got := goNames(names); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, but got %v", expected, got)
+	}
+}
+
+func TestWriteGo(t *testing.T) {
+	e := Export{
+		Name:   "shot",
+		Prefix: "1st",
+		Naming: "hex",
+		Colors: RGBColorPairSlice{
+			{rgb: RGB{0x3a, 0x7b, 0xd5}},
+			{rgb: RGB{255, 255, 255}},
+			{rgb: RGB{0x3a, 0x7b, 0xd5}},
+		},
+	}
+
+	var b strings.Builder
+	if err := writeGo(&b, e); err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "palette.go", b.String(), 0)
+	if err != nil {
+		t.Fatalf("expected valid Go, but got %v in\n%s", err, b.String())
+	}
+	// type checking catches redeclared variables, which parse fine
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("palette", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("expected the palette to compile, but got %v in\n%s", err, b.String())
+	}
+	if file.Name.Name != "palette" {
+		t.Errorf("expected package palette, but got %s", file.Name.Name)
+	}
+
+	var idents []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.ValueSpec); ok {
+			for _, name := range spec.Names {
+				idents = append(idents, name.Name)
+			}
+		}
+		return true
+	})
+	expected := []string{"C1st3a7bd5", "C1stFfffff", "C1st3a7bd52"}
+	if !slices.Equal(idents, expected) {
+		t.Errorf("expected the variables %v, but got %v", expected, idents)
+	}
+	for _, ident := range idents {
+		if !token.IsExported(ident) {
+			t.Errorf("expected %s to be exported", ident)
+		}
+	}
+}
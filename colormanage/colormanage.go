@@ -0,0 +1,264 @@
+// Package colormanage reads the color space information of a PNG file (the
+// gAMA, cHRM, sRGB and iCCP chunks) and converts pixels into sRGB.
+package colormanage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/aaronbittel/color-picker/pngchunk"
+)
+
+var renderingIntents = []string{"perceptual", "relative colorimetric", "saturation", "absolute colorimetric"}
+
+// Profile describes the color space an image was encoded in and how to get
+// from there to sRGB.
+type Profile struct {
+	// Source names the chunk the profile was taken from: "iCCP", "sRGB",
+	// "gAMA/cHRM" or "" for untagged images.
+	Source string
+
+	// Name is the profile name stored in the iCCP chunk.
+	Name string
+	// Description is the description tag of the ICC profile.
+	Description string
+	// Version is the ICC profile version.
+	Version string
+	// Intent is the rendering intent of the sRGB chunk.
+	Intent string
+
+	Gamma        float64
+	Chromaticity *Chromaticity
+
+	identity bool
+	trc      [3]curve
+	toSRGB   Mat3
+}
+
+// Chromaticity holds the values of a cHRM chunk.
+type Chromaticity struct {
+	White, Red, Green, Blue [2]float64
+}
+
+// Load reads the color chunks in front of the image data. Images without
+// any color information are assumed to be sRGB already.
+func Load(r io.Reader) (*Profile, error) {
+	var (
+		iccp, srgb, gama, chrm []byte
+	)
+
+	err := pngchunk.Walk(r, func(c pngchunk.Chunk) bool {
+		switch c.Type {
+		case "iCCP":
+			iccp = c.Data
+		case "sRGB":
+			srgb = c.Data
+		case "gAMA":
+			gama = c.Data
+		case "cHRM":
+			chrm = c.Data
+		case "IDAT":
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case iccp != nil:
+		return fromICCP(iccp)
+	case srgb != nil:
+		p := &Profile{Source: "sRGB", identity: true}
+		if len(srgb) == 1 && int(srgb[0]) < len(renderingIntents) {
+			p.Intent = renderingIntents[srgb[0]]
+		}
+		return p, nil
+	case gama != nil || chrm != nil:
+		return fromGAMAcHRM(gama, chrm)
+	default:
+		return &Profile{identity: true}, nil
+	}
+}
+
+func fromICCP(data []byte) (*Profile, error) {
+	name, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(rest) < 1 {
+		return nil, fmt.Errorf("colormanage: malformed iCCP chunk")
+	}
+	if rest[0] != 0 {
+		return nil, fmt.Errorf("colormanage: unknown iCCP compression method %d", rest[0])
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(rest[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("colormanage: decompressing ICC profile: %v", err)
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("colormanage: decompressing ICC profile: %v", err)
+	}
+
+	p := &Profile{Source: "iCCP", Name: string(name)}
+
+	icc, err := parseICC(raw)
+	p.Version = icc.version
+	p.Description = icc.description
+	if err != nil {
+		return p, err
+	}
+
+	p.trc = icc.trc
+	p.toSRGB = xyzD50ToLinearSRGB.Mul(icc.toXYZ)
+	return p, nil
+}
+
+func fromGAMAcHRM(gama, chrm []byte) (*Profile, error) {
+	p := &Profile{Source: "gAMA/cHRM"}
+
+	p.trc = [3]curve{srgbCurve, srgbCurve, srgbCurve}
+	if gama != nil {
+		if len(gama) != 4 {
+			return nil, fmt.Errorf("colormanage: gAMA chunk has %d bytes, want 4", len(gama))
+		}
+		p.Gamma = float64(binary.BigEndian.Uint32(gama)) / 100000
+		if p.Gamma == 0 {
+			return nil, fmt.Errorf("colormanage: gAMA of zero")
+		}
+		g := gammaCurve(1 / p.Gamma)
+		p.trc = [3]curve{g, g, g}
+	}
+
+	// Without cHRM the primaries are those of sRGB.
+	chroma := Chromaticity{
+		White: [2]float64{0.3127, 0.3290},
+		Red:   [2]float64{0.64, 0.33},
+		Green: [2]float64{0.30, 0.60},
+		Blue:  [2]float64{0.15, 0.06},
+	}
+	if chrm != nil {
+		if len(chrm) != 32 {
+			return nil, fmt.Errorf("colormanage: cHRM chunk has %d bytes, want 32", len(chrm))
+		}
+		value := func(i int) float64 {
+			return float64(binary.BigEndian.Uint32(chrm[4*i:])) / 100000
+		}
+		chroma = Chromaticity{
+			White: [2]float64{value(0), value(1)},
+			Red:   [2]float64{value(2), value(3)},
+			Green: [2]float64{value(4), value(5)},
+			Blue:  [2]float64{value(6), value(7)},
+		}
+		p.Chromaticity = &chroma
+
+		for _, xy := range [][2]float64{chroma.White, chroma.Red, chroma.Green, chroma.Blue} {
+			if xy[1] <= 0 {
+				return nil, fmt.Errorf("colormanage: cHRM chromaticity x = %g, y = %g is not a color", xy[0], xy[1])
+			}
+		}
+	}
+
+	toXYZ, err := chromaticityMatrix(chroma.White, chroma.Red, chroma.Green, chroma.Blue)
+	if err != nil {
+		return nil, err
+	}
+	toD50, err := Adapt(XYToXYZ(chroma.White[0], chroma.White[1]), D50)
+	if err != nil {
+		return nil, err
+	}
+
+	p.toSRGB = xyzD50ToLinearSRGB.Mul(toD50).Mul(toXYZ)
+	if !p.toSRGB.finite() {
+		return nil, fmt.Errorf("colormanage: cHRM chunk does not convert to sRGB")
+	}
+	return p, nil
+}
+
+// IsSRGB reports whether pixels can be used as they are.
+func (p *Profile) IsSRGB() bool {
+	return p.identity
+}
+
+// Convert maps a color from the profile's color space into sRGB. Colors
+// outside of the sRGB gamut are clipped. Alpha is kept as is.
+func (p *Profile) Convert(c color.Color) color.NRGBA64 {
+	n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	if p.identity {
+		return n
+	}
+
+	linear := Vec3{
+		p.trc[0].linear(float64(n.R) / 0xffff),
+		p.trc[1].linear(float64(n.G) / 0xffff),
+		p.trc[2].linear(float64(n.B) / 0xffff),
+	}
+	out := p.toSRGB.MulVec(linear)
+
+	return color.NRGBA64{
+		R: encode16(out[0]),
+		G: encode16(out[1]),
+		B: encode16(out[2]),
+		A: n.A,
+	}
+}
+
+// DecodeSRGB and EncodeSRGB apply and undo the sRGB transfer function on
+// values of 0..1.
+func DecodeSRGB(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func EncodeSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// encode16 clips linear light to 0..1 and encodes it as a 16 bit sRGB
+// sample.
+func encode16(v float64) uint16 {
+	return uint16(math.Round(EncodeSRGB(min(max(v, 0), 1)) * 0xffff))
+}
+
+func (p *Profile) String() string {
+	switch p.Source {
+	case "":
+		return "untagged (assumed sRGB)"
+	case "sRGB":
+		if p.Intent != "" {
+			return fmt.Sprintf("sRGB chunk (%s)", p.Intent)
+		}
+		return "sRGB chunk"
+	case "iCCP":
+		desc := p.Description
+		if desc == "" {
+			desc = p.Name
+		}
+		return fmt.Sprintf("ICC profile %q (v%s, embedded as %q)", desc, p.Version, p.Name)
+	default:
+		parts := []string{}
+		if p.Gamma != 0 {
+			parts = append(parts, fmt.Sprintf("gAMA %.5f", p.Gamma))
+		}
+		if c := p.Chromaticity; c != nil {
+			parts = append(parts, fmt.Sprintf(
+				"cHRM white (%.4f, %.4f) red (%.4f, %.4f) green (%.4f, %.4f) blue (%.4f, %.4f)",
+				c.White[0], c.White[1], c.Red[0], c.Red[1],
+				c.Green[0], c.Green[1], c.Blue[0], c.Blue[1]))
+		}
+		return strings.Join(parts, ", ")
+	}
+}
@@ -0,0 +1,276 @@
+package colormanage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/aaronbittel/color-picker/pngchunk"
+)
+
+func s15(v float64) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(int32(v*65536+0.5)))
+}
+
+// displayP3 builds a v4 matrix/TRC profile equivalent to Apple's Display P3.
+func displayP3() []byte {
+	xyz := func(x, y, z float64) []byte {
+		tag := []byte("XYZ \x00\x00\x00\x00")
+		tag = append(tag, s15(x)...)
+		tag = append(tag, s15(y)...)
+		return append(tag, s15(z)...)
+	}
+
+	para := []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		para = append(para, s15(v)...)
+	}
+
+	name := utf16.Encode([]rune("Display P3"))
+	desc := []byte("mluc\x00\x00\x00\x00")
+	desc = binary.BigEndian.AppendUint32(desc, 1)
+	desc = binary.BigEndian.AppendUint32(desc, 12)
+	desc = append(desc, "enUS"...)
+	desc = binary.BigEndian.AppendUint32(desc, uint32(2*len(name)))
+	desc = binary.BigEndian.AppendUint32(desc, 28)
+	for _, u := range name {
+		desc = binary.BigEndian.AppendUint16(desc, u)
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc},
+		{"rXYZ", xyz(0.515121, 0.241182, -0.001053)},
+		{"gXYZ", xyz(0.291977, 0.692236, 0.041885)},
+		{"bXYZ", xyz(0.157104, 0.066574, 0.784073)},
+		{"rTRC", para},
+		{"gTRC", para},
+		{"bTRC", para},
+	}
+
+	header := make([]byte, 128)
+	header[8] = 4
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	copy(header[36:], "acsp")
+
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	var body []byte
+	offset := 128 + 4 + 12*len(tags)
+	for _, tag := range tags {
+		table = append(table, tag.sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(body)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.data)))
+		body = append(body, tag.data...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	profile := append(header, table...)
+	profile = append(profile, body...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func pngWithChunk(t *testing.T, extra pngchunk.Chunk) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := pngchunk.ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks = append(chunks[:1], append([]pngchunk.Chunk{extra}, chunks[1:]...)...)
+	var out bytes.Buffer
+	if err := pngchunk.WriteAll(&out, chunks); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func iccpChunk(name string, profile []byte) pngchunk.Chunk {
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write(profile)
+	w.Close()
+
+	data := append([]byte(name), 0, 0)
+	return pngchunk.Chunk{Type: "iCCP", Data: append(data, compressed.Bytes()...)}
+}
+
+func near(a, b uint8) bool {
+	return max(a, b)-min(a, b) <= 2
+}
+
+func TestDisplayP3ToSRGB(t *testing.T) {
+	p, err := Load(bytes.NewReader(pngWithChunk(t, iccpChunk("ICC Profile", displayP3()))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Source != "iCCP" || p.Description != "Display P3" || p.Version != "4.0" {
+		t.Fatalf("unexpected profile %s", p)
+	}
+
+	tests := []struct {
+		in       color.NRGBA
+		expected color.NRGBA
+	}{
+		// sRGB red expressed in Display P3
+		{color.NRGBA{234, 51, 35, 255}, color.NRGBA{255, 0, 0, 255}},
+		// the P3 primaries are outside of sRGB and get clipped
+		{color.NRGBA{0, 255, 0, 255}, color.NRGBA{0, 255, 0, 255}},
+		// grays are the same in both spaces
+		{color.NRGBA{128, 128, 128, 255}, color.NRGBA{128, 128, 128, 255}},
+		{color.NRGBA{255, 255, 255, 100}, color.NRGBA{255, 255, 255, 100}},
+	}
+
+	for _, tt := range tests {
+		got := color.NRGBAModel.Convert(p.Convert(tt.in)).(color.NRGBA)
+		if !near(got.R, tt.expected.R) || !near(got.G, tt.expected.G) ||
+			!near(got.B, tt.expected.B) || got.A != tt.expected.A {
+			t.Errorf("%v: expected %v, but got %v", tt.in, tt.expected, got)
+		}
+	}
+}
+
+func TestGammaOnly(t *testing.T) {
+	gama := pngchunk.Chunk{Type: "gAMA", Data: binary.BigEndian.AppendUint32(nil, 100000)}
+	p, err := Load(bytes.NewReader(pngWithChunk(t, gama)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a gamma of 1.0 means the samples are linear light
+	got := color.NRGBAModel.Convert(p.Convert(color.NRGBA{55, 55, 55, 255})).(color.NRGBA)
+	if !near(got.R, 130) || got.R != got.G || got.G != got.B {
+		t.Errorf("expected linear 55 to encode to about 130, but got %v", got)
+	}
+}
+
+func TestUntaggedIsSRGB(t *testing.T) {
+	p, err := Load(bytes.NewReader(pngWithChunk(t, pngchunk.Chunk{Type: "tEXt", Data: []byte("a\x00b")})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsSRGB() {
+		t.Errorf("expected untagged image to be sRGB, got %s", p)
+	}
+}
+
+func TestParametricCurve(t *testing.T) {
+	para := func(function uint16, values ...float64) []byte {
+		tag := binary.BigEndian.AppendUint16([]byte("para\x00\x00\x00\x00"), function)
+		tag = append(tag, 0, 0)
+		for _, v := range values {
+			tag = append(tag, s15(v)...)
+		}
+		return tag
+	}
+
+	tests := []struct {
+		name string
+		tag  []byte
+		err  bool
+	}{
+		{"gamma", para(0, 2.2), false},
+		{"type 1", para(1, 2.2, 1, 0), false},
+		{"type 1 with a = 0", para(1, 2.2, 0, 0.5), true},
+		{"type 2 with a = 0", para(2, 2.2, 0, 0.5, 0.1), true},
+		{"type 3 with a = 0", para(3, 2.2, 0, 0, 1/12.92, 0.04), false},
+		{"unknown type", para(5, 2.2), true},
+		{"truncated", para(4, 2.2, 1), true},
+	}
+
+	for _, tt := range tests {
+		c, err := parseCurve(tt.tag)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %t, but got %v", tt.name, tt.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		for _, v := range []float64{0, 0.5, 1} {
+			if got := c.linear(v); math.IsNaN(got) || math.IsInf(got, 0) {
+				t.Errorf("%s: expected a finite value for %g, but got %g", tt.name, v, got)
+			}
+		}
+	}
+}
+
+// the matrices of each pair have to undo each other
+func TestMatrixPairs(t *testing.T) {
+	pairs := [][2]Mat3{
+		{LinearSRGBToXYZ, XYZToLinearSRGB},
+		{D65ToD50, D50ToD65},
+	}
+	for _, pair := range pairs {
+		for i := range 3 {
+			var v Vec3
+			v[i] = 1
+			got := pair[1].MulVec(pair[0].MulVec(v))
+			for j := range 3 {
+				if math.Abs(got[j]-v[j]) > 1e-9 {
+					t.Errorf("expected %v, but got %v", v, got)
+					break
+				}
+			}
+		}
+	}
+
+	// the adaptation maps one white point onto the other
+	if got := D65ToD50.MulVec(D65); math.Abs(got[0]-D50[0]) > 1e-9 || math.Abs(got[2]-D50[2]) > 1e-9 {
+		t.Errorf("expected D65 to map to %v, but got %v", D50, got)
+	}
+}
+
+func TestMalformedCHRM(t *testing.T) {
+	chrm := func(values ...uint32) pngchunk.Chunk {
+		var data []byte
+		for _, v := range values {
+			data = binary.BigEndian.AppendUint32(data, v)
+		}
+		return pngchunk.Chunk{Type: "cHRM", Data: data}
+	}
+
+	tests := []struct {
+		name  string
+		chunk pngchunk.Chunk
+		err   bool
+	}{
+		{"srgb", chrm(31270, 32900, 64000, 33000, 30000, 60000, 15000, 6000), false},
+		{"white y = 0", chrm(31270, 0, 64000, 33000, 30000, 60000, 15000, 6000), true},
+		{"blue y = 0", chrm(31270, 32900, 64000, 33000, 30000, 60000, 15000, 0), true},
+		{"same primaries", chrm(31270, 32900, 64000, 33000, 64000, 33000, 15000, 6000), true},
+		{"short", chrm(31270, 32900), true},
+	}
+
+	for _, tt := range tests {
+		p, err := Load(bytes.NewReader(pngWithChunk(t, tt.chunk)))
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %t, but got %v", tt.name, tt.err, err)
+			continue
+		}
+		if err == nil {
+			got := color.NRGBAModel.Convert(p.Convert(color.NRGBA{200, 100, 50, 255})).(color.NRGBA)
+			if !near(got.R, 200) || !near(got.G, 100) || !near(got.B, 50) {
+				t.Errorf("%s: expected sRGB to stay {200 100 50}, but got %v", tt.name, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package colormanage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+var ErrUnsupportedProfile = errors.New("colormanage: unsupported ICC profile")
+
+// curve maps an encoded channel value in [0, 1] to linear light.
+type curve interface {
+	linear(v float64) float64
+}
+
+type gammaCurve float64
+
+func (g gammaCurve) linear(v float64) float64 {
+	return math.Pow(v, float64(g))
+}
+
+// tableCurve is a sampled curve, interpolated linearly between entries.
+type tableCurve []float64
+
+func (t tableCurve) linear(v float64) float64 {
+	pos := v * float64(len(t)-1)
+	i := int(pos)
+	if i >= len(t)-1 {
+		return t[len(t)-1]
+	}
+	frac := pos - float64(i)
+	return t[i]*(1-frac) + t[i+1]*frac
+}
+
+// parametricCurve implements the five function types of the ICC
+// parametricCurveType. Missing parameters are left at zero.
+type parametricCurve struct {
+	function            uint16
+	g, a, b, c, d, e, f float64
+}
+
+func (p parametricCurve) linear(v float64) float64 {
+	pow := func(x float64) float64 {
+		if x <= 0 {
+			return 0
+		}
+		return math.Pow(x, p.g)
+	}
+
+	switch p.function {
+	case 0:
+		return pow(v)
+	case 1:
+		if v >= -p.b/p.a {
+			return pow(p.a*v + p.b)
+		}
+		return 0
+	case 2:
+		if v >= -p.b/p.a {
+			return pow(p.a*v+p.b) + p.c
+		}
+		return p.c
+	case 3:
+		if v >= p.d {
+			return pow(p.a*v + p.b)
+		}
+		return p.c * v
+	default:
+		if v >= p.d {
+			return pow(p.a*v+p.b) + p.e
+		}
+		return p.c*v + p.f
+	}
+}
+
+// srgbCurve is the sRGB transfer function.
+var srgbCurve curve = srgbTRC{}
+
+type srgbTRC struct{}
+
+func (srgbTRC) linear(v float64) float64 {
+	return DecodeSRGB(v)
+}
+
+// iccProfile holds the parts of a matrix/TRC display profile needed to
+// convert into the profile connection space.
+type iccProfile struct {
+	version     string
+	description string
+	toXYZ       Mat3
+	trc         [3]curve
+}
+
+func parseICC(data []byte) (iccProfile, error) {
+	if len(data) < 132 {
+		return iccProfile{}, fmt.Errorf("colormanage: ICC profile too short (%d bytes)", len(data))
+	}
+	if string(data[36:40]) != "acsp" {
+		return iccProfile{}, fmt.Errorf("colormanage: ICC profile is missing the acsp signature")
+	}
+
+	profile := iccProfile{
+		version: fmt.Sprintf("%d.%d", data[8], data[9]>>4),
+	}
+
+	colorSpace := string(data[16:20])
+	pcs := string(data[20:24])
+	if colorSpace != "RGB " {
+		return profile, fmt.Errorf("%w: color space %q", ErrUnsupportedProfile, colorSpace)
+	}
+	if pcs != "XYZ " {
+		return profile, fmt.Errorf("%w: connection space %q", ErrUnsupportedProfile, pcs)
+	}
+
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(data[128:132]))
+	for i := range count {
+		entry := 132 + 12*i
+		if entry+12 > len(data) {
+			return profile, fmt.Errorf("colormanage: ICC tag table truncated")
+		}
+		sig := string(data[entry : entry+4])
+		offset := int(binary.BigEndian.Uint32(data[entry+4 : entry+8]))
+		size := int(binary.BigEndian.Uint32(data[entry+8 : entry+12]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			return profile, fmt.Errorf("colormanage: ICC tag %q out of bounds", sig)
+		}
+		tags[sig] = data[offset : offset+size]
+	}
+
+	if desc, ok := tags["desc"]; ok {
+		profile.description = parseText(desc)
+	}
+
+	for i, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		tag, ok := tags[sig]
+		if !ok {
+			return profile, fmt.Errorf("%w: no %s tag, only matrix/TRC profiles are supported",
+				ErrUnsupportedProfile, sig)
+		}
+		xyz, err := parseXYZ(tag)
+		if err != nil {
+			return profile, fmt.Errorf("colormanage: %s: %v", sig, err)
+		}
+		for row := range 3 {
+			profile.toXYZ[row][i] = xyz[row]
+		}
+	}
+
+	for i, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tag, ok := tags[sig]
+		if !ok {
+			return profile, fmt.Errorf("%w: no %s tag", ErrUnsupportedProfile, sig)
+		}
+		c, err := parseCurve(tag)
+		if err != nil {
+			return profile, fmt.Errorf("colormanage: %s: %v", sig, err)
+		}
+		profile.trc[i] = c
+	}
+
+	return profile, nil
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func parseXYZ(tag []byte) (Vec3, error) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return Vec3{}, fmt.Errorf("not an XYZ tag")
+	}
+	return Vec3{s15Fixed16(tag[8:12]), s15Fixed16(tag[12:16]), s15Fixed16(tag[16:20])}, nil
+}
+
+func parseCurve(tag []byte) (curve, error) {
+	if len(tag) < 12 {
+		return nil, fmt.Errorf("curve tag too short")
+	}
+
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if len(tag) < 12+2*n {
+			return nil, fmt.Errorf("curve with %d entries truncated", n)
+		}
+		switch n {
+		case 0:
+			return gammaCurve(1), nil
+		case 1:
+			return gammaCurve(float64(binary.BigEndian.Uint16(tag[12:14])) / 256), nil
+		default:
+			table := make(tableCurve, n)
+			for i := range n {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+2*i:])) / 65535
+			}
+			return table, nil
+		}
+	case "para":
+		function := binary.BigEndian.Uint16(tag[8:10])
+		params := map[uint16]int{0: 1, 1: 3, 2: 4, 3: 5, 4: 7}
+		n, ok := params[function]
+		if !ok {
+			return nil, fmt.Errorf("unknown parametric function type %d", function)
+		}
+		if len(tag) < 12+4*n {
+			return nil, fmt.Errorf("parametric curve truncated")
+		}
+		var values [7]float64
+		for i := range n {
+			values[i] = s15Fixed16(tag[12+4*i:])
+		}
+		// types 1 and 2 start at -b/a
+		if (function == 1 || function == 2) && values[1] == 0 {
+			return nil, fmt.Errorf("parametric curve of type %d with a = 0", function)
+		}
+		return parametricCurve{
+			function: function,
+			g:        values[0], a: values[1], b: values[2], c: values[3],
+			d: values[4], e: values[5], f: values[6],
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported curve type %q", tag[:4])
+	}
+}
+
+// parseText reads the profile description from an ICC v2 textDescriptionType
+// or the first record of a v4 multiLocalizedUnicodeType.
+func parseText(tag []byte) string {
+	if len(tag) < 12 {
+		return ""
+	}
+
+	switch string(tag[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if len(tag) < 12+n {
+			return ""
+		}
+		return strings.TrimRight(string(tag[12:12+n]), "\x00")
+	case "mluc":
+		if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:12]) == 0 {
+			return ""
+		}
+		length := int(binary.BigEndian.Uint32(tag[20:24]))
+		offset := int(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+length > len(tag) {
+			return ""
+		}
+		units := make([]uint16, length/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[offset+2*i:])
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	case "text":
+		return strings.TrimRight(string(tag[8:]), "\x00")
+	default:
+		return ""
+	}
+}
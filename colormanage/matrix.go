@@ -0,0 +1,180 @@
+package colormanage
+
+import (
+	"fmt"
+	"math"
+)
+
+// Vec3 is a color of three channels, such as XYZ or linear RGB.
+type Vec3 [3]float64
+
+// Mat3 is a 3x3 matrix applied to column vectors.
+type Mat3 [3][3]float64
+
+func (m Mat3) MulVec(v Vec3) Vec3 {
+	return Vec3{
+		m[0][0]*v[0] + m[0][1]*v[1] + m[0][2]*v[2],
+		m[1][0]*v[0] + m[1][1]*v[1] + m[1][2]*v[2],
+		m[2][0]*v[0] + m[2][1]*v[1] + m[2][2]*v[2],
+	}
+}
+
+// Mul returns the matrix that applies n first and then m.
+func (m Mat3) Mul(n Mat3) Mat3 {
+	var out Mat3
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return out
+}
+
+// finite reports whether no entry is infinite or NaN.
+func (m Mat3) finite() bool {
+	for _, row := range m {
+		for _, v := range row {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (m Mat3) Inverse() (Mat3, error) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if det == 0 {
+		return Mat3{}, fmt.Errorf("colormanage: singular matrix %v", m)
+	}
+
+	inv := Mat3{
+		{
+			m[1][1]*m[2][2] - m[1][2]*m[2][1],
+			m[0][2]*m[2][1] - m[0][1]*m[2][2],
+			m[0][1]*m[1][2] - m[0][2]*m[1][1],
+		},
+		{
+			m[1][2]*m[2][0] - m[1][0]*m[2][2],
+			m[0][0]*m[2][2] - m[0][2]*m[2][0],
+			m[0][2]*m[1][0] - m[0][0]*m[1][2],
+		},
+		{
+			m[1][0]*m[2][1] - m[1][1]*m[2][0],
+			m[0][1]*m[2][0] - m[0][0]*m[2][1],
+			m[0][0]*m[1][1] - m[0][1]*m[1][0],
+		},
+	}
+	for i := range 3 {
+		for j := range 3 {
+			inv[i][j] /= det
+		}
+	}
+	return inv, nil
+}
+
+// D65 is the white point of sRGB. D50 is the white point of the ICC
+// profile connection space and of CIELAB, which profiles store rounded to
+// 0.9642, 1, 0.8249.
+var (
+	D65 = XYToXYZ(0.3127, 0.3290)
+	D50 = XYToXYZ(0.3457, 0.3585)
+)
+
+var bradford = Mat3{
+	{0.8951, 0.2664, -0.1614},
+	{-0.7502, 1.7135, 0.0367},
+	{0.0389, -0.0685, 1.0296},
+}
+
+// The matrices between linear sRGB and XYZ relative to D65, as given in
+// https://www.w3.org/TR/css-color-4/#color-conversion-code
+var (
+	LinearSRGBToXYZ = Mat3{
+		{0.41239079926595934, 0.357584339383878, 0.1804807884018343},
+		{0.21263900587151027, 0.715168678767756, 0.07219231536073371},
+		{0.01933081871559182, 0.11919477979462598, 0.9505321522496607},
+	}
+	XYZToLinearSRGB = Mat3{
+		{3.2409699419045226, -1.537383177570094, -0.4986107602930034},
+		{-0.9692436362808796, 1.8759675015077202, 0.04155505740717559},
+		{0.05563007969699366, -0.20397695888897652, 1.0569715142428786},
+	}
+)
+
+// D65ToD50 and D50ToD65 are the Bradford adaptations between the white
+// points.
+var (
+	D65ToD50 = mustAdapt(D65, D50)
+	D50ToD65 = mustAdapt(D50, D65)
+)
+
+// xyzD50ToLinearSRGB converts from the profile connection space to linear
+// sRGB.
+var xyzD50ToLinearSRGB = XYZToLinearSRGB.Mul(D50ToD65)
+
+// Adapt returns the Bradford matrix that maps colors relative to the white
+// point from onto the white point to.
+func Adapt(from, to Vec3) (Mat3, error) {
+	inv, err := bradford.Inverse()
+	if err != nil {
+		return Mat3{}, err
+	}
+
+	src := bradford.MulVec(from)
+	dst := bradford.MulVec(to)
+	scale := Mat3{
+		{dst[0] / src[0], 0, 0},
+		{0, dst[1] / src[1], 0},
+		{0, 0, dst[2] / src[2]},
+	}
+
+	return inv.Mul(scale).Mul(bradford), nil
+}
+
+// mustAdapt is Adapt for the package's own white points, where the only
+// error, a singular Bradford matrix, cannot happen.
+func mustAdapt(from, to Vec3) Mat3 {
+	m, err := Adapt(from, to)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// XYToXYZ converts a chromaticity to XYZ with a luminance of 1.
+func XYToXYZ(x, y float64) Vec3 {
+	return Vec3{x / y, 1, (1 - x - y) / y}
+}
+
+// chromaticityMatrix builds the matrix that maps linear RGB with the given
+// primaries and white point to XYZ relative to the same white point.
+func chromaticityMatrix(white, red, green, blue [2]float64) (Mat3, error) {
+	r := XYToXYZ(red[0], red[1])
+	g := XYToXYZ(green[0], green[1])
+	b := XYToXYZ(blue[0], blue[1])
+	w := XYToXYZ(white[0], white[1])
+
+	primaries := Mat3{
+		{r[0], g[0], b[0]},
+		{r[1], g[1], b[1]},
+		{r[2], g[2], b[2]},
+	}
+	inv, err := primaries.Inverse()
+	if err != nil {
+		return Mat3{}, err
+	}
+
+	s := inv.MulVec(w)
+	for i := range 3 {
+		for j := range 3 {
+			primaries[i][j] *= s[j]
+		}
+	}
+
+	return primaries, nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"math"
+
+	"github.com/aaronbittel/color-picker/colormanage"
+)
+
+// Conversions between sRGB and other color spaces. Hues are in degrees,
+// everything else but Lab and LCh is of 0..1. Converting back to RGB
+// rounds and clamps the channels, so colors outside of sRGB are clipped.
+//
+// XYZ is relative to the D65 white point of sRGB, Lab and LCh to D50 like
+// the lab() and lch() of CSS Color 4, adapted with the Bradford transform.
+// The transfer function, matrices and white points are those of
+// colormanage.
+
+type HSL struct{ H, S, L float64 }
+
+type HSV struct{ H, S, V float64 }
+
+type HWB struct{ H, W, B float64 }
+
+type CMYK struct{ C, M, Y, K float64 }
+
+// LinearRGB is sRGB without the transfer function, proportional to light.
+type LinearRGB struct{ R, G, B float64 }
+
+type XYZ struct{ X, Y, Z float64 }
+
+// Lab is CIELAB with L of 0..100.
+type Lab struct{ L, A, B float64 }
+
+// LCh is the polar form of Lab, with chroma C and hue H.
+type LCh struct{ L, C, H float64 }
+
+type OKLab struct{ L, A, B float64 }
+
+type OKLCh struct{ L, C, H float64 }
+
+// floats returns the channels scaled to 0..1.
+func (rgb RGB) floats() (float64, float64, float64) {
+	return float64(rgb.red) / 255, float64(rgb.green) / 255, float64(rgb.blue) / 255
+}
+
+// fromFloats rounds and clamps channels of 0..1.
+func fromFloats(r, g, b float64) RGB {
+	return RGB{channel(r * 255), channel(g * 255), channel(b * 255)}
+}
+
+// hue is the hue in degrees shared by HSL, HSV and HWB, with the largest
+// and smallest channel scaled to 0..1.
+func hue(rgb RGB) (h, maxC, minC float64) {
+	r, g, b := rgb.floats()
+	maxC, minC = max(r, g, b), min(r, g, b)
+	d := maxC - minC
+
+	switch {
+	case d == 0:
+		h = 0
+	case maxC == r:
+		h = math.Mod((g-b)/d, 6)
+	case maxC == g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, maxC, minC
+}
+
+func (rgb RGB) HSL() HSL {
+	h, maxC, minC := hue(rgb)
+	l := (maxC + minC) / 2
+	s := 0.0
+	if maxC != minC {
+		s = (maxC - minC) / (1 - math.Abs(2*l-1))
+	}
+	return HSL{h, s, l}
+}
+
+// floats converts to channels of 0..1 without rounding.
+func (c HSL) floats() (float64, float64, float64) {
+	f := func(n float64) float64 {
+		k := math.Mod(n+c.H/30, 12)
+		a := c.S * min(c.L, 1-c.L)
+		return c.L - a*max(-1, min(k-3, 9-k, 1))
+	}
+	return f(0), f(8), f(4)
+}
+
+func (c HSL) RGB() RGB {
+	return fromFloats(c.floats())
+}
+
+func (rgb RGB) HSV() HSV {
+	h, maxC, minC := hue(rgb)
+	s := 0.0
+	if maxC != 0 {
+		s = (maxC - minC) / maxC
+	}
+	return HSV{h, s, maxC}
+}
+
+func (c HSV) RGB() RGB {
+	f := func(n float64) float64 {
+		k := math.Mod(n+c.H/60, 6)
+		return c.V - c.V*c.S*max(0, min(k, 4-k, 1))
+	}
+	return fromFloats(f(5), f(3), f(1))
+}
+
+func (rgb RGB) HWB() HWB {
+	h, maxC, minC := hue(rgb)
+	return HWB{h, minC, 1 - maxC}
+}
+
+// RGB normalizes whiteness and blackness adding up to more than 1 to a
+// gray, like CSS.
+func (c HWB) RGB() RGB {
+	if c.W+c.B >= 1 {
+		gray := c.W / (c.W + c.B)
+		return fromFloats(gray, gray, gray)
+	}
+	r, g, b := HSL{c.H, 1, 0.5}.floats()
+	scale := func(v float64) float64 { return v*(1-c.W-c.B) + c.W }
+	return fromFloats(scale(r), scale(g), scale(b))
+}
+
+// CMYK is the naive conversion without a printer profile.
+func (rgb RGB) CMYK() CMYK {
+	r, g, b := rgb.floats()
+	k := 1 - max(r, g, b)
+	if k == 1 {
+		return CMYK{0, 0, 0, 1}
+	}
+	return CMYK{(1 - r - k) / (1 - k), (1 - g - k) / (1 - k), (1 - b - k) / (1 - k), k}
+}
+
+func (c CMYK) RGB() RGB {
+	return fromFloats((1-c.C)*(1-c.K), (1-c.M)*(1-c.K), (1-c.Y)*(1-c.K))
+}
+
+func (rgb RGB) Linear() LinearRGB {
+	r, g, b := rgb.floats()
+	return LinearRGB{colormanage.DecodeSRGB(r), colormanage.DecodeSRGB(g), colormanage.DecodeSRGB(b)}
+}
+
+func (c LinearRGB) RGB() RGB {
+	return fromFloats(colormanage.EncodeSRGB(c.R), colormanage.EncodeSRGB(c.G), colormanage.EncodeSRGB(c.B))
+}
+
+func (c LinearRGB) XYZ() XYZ {
+	v := colormanage.LinearSRGBToXYZ.MulVec(colormanage.Vec3{c.R, c.G, c.B})
+	return XYZ{v[0], v[1], v[2]}
+}
+
+func (c XYZ) Linear() LinearRGB {
+	v := colormanage.XYZToLinearSRGB.MulVec(colormanage.Vec3{c.X, c.Y, c.Z})
+	return LinearRGB{v[0], v[1], v[2]}
+}
+
+func (rgb RGB) XYZ() XYZ {
+	return rgb.Linear().XYZ()
+}
+
+func (c XYZ) RGB() RGB {
+	return c.Linear().RGB()
+}
+
+// Constants of CIELAB, as exact fractions.
+const (
+	LAB_EPSILON = 216.0 / 24389
+	LAB_KAPPA   = 24389.0 / 27
+)
+
+func (c XYZ) Lab() Lab {
+	v := colormanage.D65ToD50.MulVec(colormanage.Vec3{c.X, c.Y, c.Z})
+	f := func(t float64) float64 {
+		if t > LAB_EPSILON {
+			return math.Cbrt(t)
+		}
+		return (LAB_KAPPA*t + 16) / 116
+	}
+	white := colormanage.D50
+	fx, fy, fz := f(v[0]/white[0]), f(v[1]/white[1]), f(v[2]/white[2])
+	return Lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func (c Lab) XYZ() XYZ {
+	fy := (c.L + 16) / 116
+	fx := c.A/500 + fy
+	fz := fy - c.B/200
+	finv := func(f float64) float64 {
+		if t := f * f * f; t > LAB_EPSILON {
+			return t
+		}
+		return (116*f - 16) / LAB_KAPPA
+	}
+
+	y := c.L / LAB_KAPPA
+	if c.L > LAB_KAPPA*LAB_EPSILON {
+		y = fy * fy * fy
+	}
+	white := colormanage.D50
+	v := colormanage.D50ToD65.MulVec(colormanage.Vec3{finv(fx) * white[0], y * white[1], finv(fz) * white[2]})
+	return XYZ{v[0], v[1], v[2]}
+}
+
+func (rgb RGB) Lab() Lab {
+	return rgb.XYZ().Lab()
+}
+
+func (c Lab) RGB() RGB {
+	return c.XYZ().RGB()
+}
+
+// polar converts a and b to chroma and a hue of 0..360.
+func polar(a, b float64) (float64, float64) {
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return math.Hypot(a, b), h
+}
+
+func cartesian(c, h float64) (float64, float64) {
+	rad := h * math.Pi / 180
+	return c * math.Cos(rad), c * math.Sin(rad)
+}
+
+func (c Lab) LCh() LCh {
+	chroma, h := polar(c.A, c.B)
+	return LCh{c.L, chroma, h}
+}
+
+func (c LCh) Lab() Lab {
+	a, b := cartesian(c.C, c.H)
+	return Lab{c.L, a, b}
+}
+
+func (rgb RGB) LCh() LCh {
+	return rgb.Lab().LCh()
+}
+
+func (c LCh) RGB() RGB {
+	return c.Lab().RGB()
+}
+
+// OKLab is converted with the matrices of
+// https://bottosson.github.io/posts/oklab/
+func (c LinearRGB) OKLab() OKLab {
+	l := math.Cbrt(0.4122214708*c.R + 0.5363325363*c.G + 0.0514459929*c.B)
+	m := math.Cbrt(0.2119034982*c.R + 0.6806995451*c.G + 0.1073969566*c.B)
+	s := math.Cbrt(0.0883024619*c.R + 0.2817188376*c.G + 0.6299787005*c.B)
+
+	return OKLab{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func (c OKLab) Linear() LinearRGB {
+	l := math.Pow(c.L+0.3963377774*c.A+0.2158037573*c.B, 3)
+	m := math.Pow(c.L-0.1055613458*c.A-0.0638541728*c.B, 3)
+	s := math.Pow(c.L-0.0894841775*c.A-1.2914855480*c.B, 3)
+
+	return LinearRGB{
+		4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		-1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		-0.0041960863*l - 0.7034186147*m + 1.7076147010*s,
+	}
+}
+
+func (rgb RGB) OKLab() OKLab {
+	return rgb.Linear().OKLab()
+}
+
+func (c OKLab) RGB() RGB {
+	return c.Linear().RGB()
+}
+
+func (c OKLab) OKLCh() OKLCh {
+	chroma, h := polar(c.A, c.B)
+	return OKLCh{c.L, chroma, h}
+}
+
+func (c OKLCh) OKLab() OKLab {
+	a, b := cartesian(c.C, c.H)
+	return OKLab{c.L, a, b}
+}
+
+func (rgb RGB) OKLCh() OKLCh {
+	return rgb.OKLab().OKLCh()
+}
+
+func (c OKLCh) RGB() RGB {
+	return c.OKLab().RGB()
+}
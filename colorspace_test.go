@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// SPACES convert to every color space and back.
+var SPACES = map[string]func(RGB) RGB{
+	"hsl":    func(c RGB) RGB { return c.HSL().RGB() },
+	"hsv":    func(c RGB) RGB { return c.HSV().RGB() },
+	"hwb":    func(c RGB) RGB { return c.HWB().RGB() },
+	"cmyk":   func(c RGB) RGB { return c.CMYK().RGB() },
+	"linear": func(c RGB) RGB { return c.Linear().RGB() },
+	"xyz":    func(c RGB) RGB { return c.XYZ().RGB() },
+	"lab":    func(c RGB) RGB { return c.Lab().RGB() },
+	"lch":    func(c RGB) RGB { return c.LCh().RGB() },
+	"oklab":  func(c RGB) RGB { return c.OKLab().RGB() },
+	"oklch":  func(c RGB) RGB { return c.OKLCh().RGB() },
+}
+
+func TestColorSpaceRoundTrip(t *testing.T) {
+	var steps []uint8
+	for v := 0; v < 256; v += 5 {
+		steps = append(steps, uint8(v))
+	}
+	steps = append(steps, 1, 2, 127, 128, 254)
+
+	for name, roundTrip := range SPACES {
+		failures := 0
+		for _, r := range steps {
+			for _, g := range steps {
+				for _, b := range steps {
+					rgb := RGB{r, g, b}
+					if got := roundTrip(rgb); got != rgb {
+						t.Errorf("%s: %v came back as %v", name, rgb, got)
+						failures++
+					}
+					if failures > 5 {
+						t.FailNow()
+					}
+				}
+			}
+		}
+	}
+}
+
+func closeTo(got, expected []float64, tolerance float64) bool {
+	for i := range got {
+		if math.Abs(got[i]-expected[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestColorSpaceValues(t *testing.T) {
+	red := RGB{255, 0, 0}
+	tests := []struct {
+		name      string
+		got       []float64
+		expected  []float64
+		tolerance float64
+	}{
+		{"hsl", hslValues(RGB{110, 35, 30}.HSL()), []float64{3.75, 0.5714, 0.2745}, 0.0001},
+		{"hsv", hsvValues(RGB{110, 35, 30}.HSV()), []float64{3.75, 0.7273, 0.4314}, 0.0001},
+		{"hwb", hwbValues(RGB{110, 35, 30}.HWB()), []float64{3.75, 0.1176, 0.5686}, 0.0001},
+		{"cmyk", cmykValues(RGB{255, 128, 0}.CMYK()), []float64{0, 0.498, 1, 0}, 0.001},
+		{"cmyk black", cmykValues(RGB{0, 0, 0}.CMYK()), []float64{0, 0, 0, 1}, 0},
+		{"linear", linearValues(RGB{128, 128, 128}.Linear()), []float64{0.2159, 0.2159, 0.2159}, 0.0001},
+		{"xyz white", xyzValues(RGB{255, 255, 255}.XYZ()), []float64{0.9505, 1, 1.0891}, 0.0001},
+		{"lab white", labValues(RGB{255, 255, 255}.Lab()), []float64{100, 0, 0}, 0.001},
+		{"lab red", labValues(red.Lab()), []float64{54.29, 80.80, 69.89}, 0.01},
+		{"lch red", lchValues(red.LCh()), []float64{54.29, 106.84, 40.85}, 0.01},
+		{"oklab white", oklabValues(RGB{255, 255, 255}.OKLab()), []float64{1, 0, 0}, 0.0001},
+		{"oklch red", oklchValues(red.OKLCh()), []float64{0.628, 0.2577, 29.234}, 0.001},
+	}
+
+	for _, tt := range tests {
+		if !closeTo(tt.got, tt.expected, tt.tolerance) {
+			t.Errorf("%s: expected %v, but got %v", tt.name, tt.expected, tt.got)
+		}
+	}
+}
+
+func hslValues(c HSL) []float64          { return []float64{c.H, c.S, c.L} }
+func hsvValues(c HSV) []float64          { return []float64{c.H, c.S, c.V} }
+func hwbValues(c HWB) []float64          { return []float64{c.H, c.W, c.B} }
+func cmykValues(c CMYK) []float64        { return []float64{c.C, c.M, c.Y, c.K} }
+func linearValues(c LinearRGB) []float64 { return []float64{c.R, c.G, c.B} }
+func xyzValues(c XYZ) []float64          { return []float64{c.X, c.Y, c.Z} }
+func labValues(c Lab) []float64          { return []float64{c.L, c.A, c.B} }
+func lchValues(c LCh) []float64          { return []float64{c.L, c.C, c.H} }
+func oklabValues(c OKLab) []float64      { return []float64{c.L, c.A, c.B} }
+func oklchValues(c OKLCh) []float64      { return []float64{c.L, c.C, c.H} }
@@ -0,0 +1,245 @@
+package exploring
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// APNG extends png with three ancillary chunks: acTL announces the
+// animation, every frame starts with an fcTL chunk and the data of all
+// frames but the default image is stored in fdAT chunks.
+// https://wiki.mozilla.org/APNG_Specification
+
+type AnimationControl struct {
+	NumFrames uint32
+	// NumPlays is the number of loops, 0 means infinite.
+	NumPlays uint32
+}
+
+type DisposeOp byte
+
+const (
+	DISPOSE_NONE DisposeOp = iota
+	DISPOSE_BACKGROUND
+	DISPOSE_PREVIOUS
+)
+
+func (d DisposeOp) String() string {
+	switch d {
+	case DISPOSE_NONE:
+		return "none"
+	case DISPOSE_BACKGROUND:
+		return "background"
+	case DISPOSE_PREVIOUS:
+		return "previous"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(d))
+	}
+}
+
+type BlendOp byte
+
+const (
+	BLEND_SOURCE BlendOp = iota
+	BLEND_OVER
+)
+
+func (b BlendOp) String() string {
+	switch b {
+	case BLEND_SOURCE:
+		return "source"
+	case BLEND_OVER:
+		return "over"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(b))
+	}
+}
+
+type FrameControl struct {
+	Sequence uint32
+	Width    int
+	Height   int
+	XOffset  int
+	YOffset  int
+	DelayNum uint16
+	DelayDen uint16
+	Dispose  DisposeOp
+	Blend    BlendOp
+}
+
+// Delay is the time the frame is shown in seconds.
+func (fc FrameControl) Delay() float64 {
+	den := fc.DelayDen
+	if den == 0 {
+		den = 100
+	}
+	return float64(fc.DelayNum) / float64(den)
+}
+
+type Frame struct {
+	Control FrameControl
+	// IsDefault is set if the frame's data is the IDAT image.
+	IsDefault bool
+	Data      []byte
+	// Image is the whole canvas after the frame was rendered onto it.
+	Image Image
+}
+
+func (png *PNG) parseACTL(chunk Chunk) error {
+	if len(chunk.Data) != 8 {
+		return fmt.Errorf("error: acTL chunk must be 8 bytes, got %d", len(chunk.Data))
+	}
+
+	png.Animation = &AnimationControl{
+		NumFrames: binary.BigEndian.Uint32(chunk.Data[0:4]),
+		NumPlays:  binary.BigEndian.Uint32(chunk.Data[4:8]),
+	}
+	if png.Animation.NumFrames == 0 {
+		return fmt.Errorf("error: acTL chunk with 0 frames")
+	}
+
+	return nil
+}
+
+// checkSequence verifies the sequence number fcTL and fdAT chunks start
+// with. Together they count up from 0.
+func (png *PNG) checkSequence(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("error: animation chunk without sequence number")
+	}
+
+	sequence := binary.BigEndian.Uint32(data)
+	if int(sequence) != png.sequence {
+		return fmt.Errorf("error: animation chunk has sequence number %d, expected %d",
+			sequence, png.sequence)
+	}
+	png.sequence++
+
+	return nil
+}
+
+func (png *PNG) parseFCTL(chunk Chunk) (FrameControl, error) {
+	data := chunk.Data
+	if len(data) != 26 {
+		return FrameControl{}, fmt.Errorf("error: fcTL chunk must be 26 bytes, got %d", len(data))
+	}
+	if err := png.checkSequence(data); err != nil {
+		return FrameControl{}, err
+	}
+
+	fc := FrameControl{
+		Sequence: binary.BigEndian.Uint32(data[0:4]),
+		Width:    int(binary.BigEndian.Uint32(data[4:8])),
+		Height:   int(binary.BigEndian.Uint32(data[8:12])),
+		XOffset:  int(binary.BigEndian.Uint32(data[12:16])),
+		YOffset:  int(binary.BigEndian.Uint32(data[16:20])),
+		DelayNum: binary.BigEndian.Uint16(data[20:22]),
+		DelayDen: binary.BigEndian.Uint16(data[22:24]),
+		Dispose:  DisposeOp(data[24]),
+		Blend:    BlendOp(data[25]),
+	}
+
+	if fc.Width <= 0 || fc.Height <= 0 ||
+		fc.XOffset+fc.Width > png.IHDR.Width || fc.YOffset+fc.Height > png.IHDR.Height {
+		return FrameControl{}, fmt.Errorf("error: frame %dx%d at (%d, %d) is outside of the %dx%d canvas",
+			fc.Width, fc.Height, fc.XOffset, fc.YOffset, png.IHDR.Width, png.IHDR.Height)
+	}
+	if fc.Dispose > DISPOSE_PREVIOUS {
+		return FrameControl{}, fmt.Errorf("error: unknown dispose op %d", fc.Dispose)
+	}
+	if fc.Blend > BLEND_OVER {
+		return FrameControl{}, fmt.Errorf("error: unknown blend op %d", fc.Blend)
+	}
+
+	return fc, nil
+}
+
+// renderFrames decodes every frame and composes it onto the canvas, applying
+// the blend and dispose ops of the frames before it.
+func (png *PNG) renderFrames() error {
+	if png.Animation == nil {
+		png.Frames = nil
+		return nil
+	}
+	if len(png.Frames) != int(png.Animation.NumFrames) {
+		return fmt.Errorf("error: acTL announces %d frames, but found %d",
+			png.Animation.NumFrames, len(png.Frames))
+	}
+
+	canvas := NewImage(uint32(png.IHDR.Width), uint32(png.IHDR.Height))
+
+	for i := range png.Frames {
+		frame := &png.Frames[i]
+		fc := frame.Control
+
+		src := png.Image
+		if !frame.IsDefault {
+			var err error
+			src, _, err = png.decodeImage(frame.Data, fc.Width, fc.Height)
+			if err != nil {
+				return fmt.Errorf("error: decoding frame %d: %v", i, err)
+			}
+		}
+
+		dispose := fc.Dispose
+		if i == 0 && dispose == DISPOSE_PREVIOUS {
+			dispose = DISPOSE_BACKGROUND
+		}
+
+		var previous Image
+		if dispose == DISPOSE_PREVIOUS {
+			previous = canvas.clone()
+		}
+
+		for y := range fc.Height {
+			for x := range fc.Width {
+				dst := &canvas.Pixels[fc.YOffset+y][fc.XOffset+x]
+				if fc.Blend == BLEND_OVER {
+					*dst = over(src.Pixels[y][x], *dst)
+				} else {
+					*dst = src.Pixels[y][x]
+				}
+			}
+		}
+
+		frame.Image = canvas.clone()
+
+		switch dispose {
+		case DISPOSE_BACKGROUND:
+			for y := range fc.Height {
+				for x := range fc.Width {
+					canvas.Pixels[fc.YOffset+y][fc.XOffset+x] = Pixel{}
+				}
+			}
+		case DISPOSE_PREVIOUS:
+			canvas = previous
+		}
+	}
+
+	return nil
+}
+
+// over composites src onto dst with non-premultiplied alpha.
+func over(src, dst Pixel) Pixel {
+	if src.Alpha == 0xffff || dst.Alpha == 0 {
+		return src
+	}
+	if src.Alpha == 0 {
+		return dst
+	}
+
+	sa := float64(src.Alpha) / 0xffff
+	da := float64(dst.Alpha) / 0xffff * (1 - sa)
+	outAlpha := sa + da
+
+	blend := func(s, d uint16) uint16 {
+		return uint16((float64(s)*sa+float64(d)*da)/outAlpha + 0.5)
+	}
+
+	return Pixel{
+		Red:   blend(src.Red, dst.Red),
+		Green: blend(src.Green, dst.Green),
+		Blue:  blend(src.Blue, dst.Blue),
+		Alpha: uint16(outAlpha*0xffff + 0.5),
+	}
+}
@@ -0,0 +1,106 @@
+package exploring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func solid(width, height uint32, p Pixel) Image {
+	img := NewImage(width, height)
+	for y := range img.Pixels {
+		for x := range img.Pixels[y] {
+			img.Pixels[y][x] = p
+		}
+	}
+	return img
+}
+
+func fcTL(sequence, width, height, x, y uint32, dispose DisposeOp, blend BlendOp) []byte {
+	var data []byte
+	for _, v := range []uint32{sequence, width, height, x, y} {
+		data = binary.BigEndian.AppendUint32(data, v)
+	}
+	data = binary.BigEndian.AppendUint16(data, 1)
+	data = binary.BigEndian.AppendUint16(data, 10)
+	data = append(data, byte(dispose), byte(blend))
+	return ChunkBytes("fcTL", data)
+}
+
+func frameData(t *testing.T, img Image) []byte {
+	t.Helper()
+	data, err := compress_zlib(filterImage(img, 6, FILTER_NONE), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadAPNG(t *testing.T) {
+	red := Pixel{0xffff, 0, 0, 0xffff}
+	blue := Pixel{0, 0, 0xffff, 0x8080}
+	green := Pixel{0, 0xffff, 0, 0xffff}
+
+	var file []byte
+	file = append(file, PNG_SIGNATURE...)
+	file = append(file, IHDRChunk(4, 4, 6)...)
+	file = append(file, ChunkBytes("acTL", []byte{0, 0, 0, 3, 0, 0, 0, 0})...)
+	file = append(file, fcTL(0, 4, 4, 0, 0, DISPOSE_NONE, BLEND_SOURCE)...)
+	file = append(file, IDATChunk(frameData(t, solid(4, 4, red)))...)
+	file = append(file, fcTL(1, 2, 2, 1, 1, DISPOSE_BACKGROUND, BLEND_OVER)...)
+	file = append(file, ChunkBytes("fdAT", append([]byte{0, 0, 0, 2}, frameData(t, solid(2, 2, blue))...))...)
+	file = append(file, fcTL(3, 1, 1, 0, 0, DISPOSE_PREVIOUS, BLEND_SOURCE)...)
+	file = append(file, ChunkBytes("fdAT", append([]byte{0, 0, 0, 4}, frameData(t, solid(1, 1, green))...))...)
+	file = append(file, IENDChunk()...)
+
+	png, err := ReadPNG(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if png.Animation == nil || len(png.Frames) != 3 {
+		t.Fatalf("expected 3 frames, got %+v", png.Animation)
+	}
+
+	blended := over(blue, red)
+	if blended.Alpha != 0xffff || blended.Red != 0x7f7f || blended.Blue != 0x8080 {
+		t.Fatalf("unexpected blend result %v", blended)
+	}
+
+	tests := []struct {
+		frame    int
+		x, y     int
+		expected Pixel
+	}{
+		{0, 2, 2, red},
+		{1, 0, 0, red},
+		{1, 1, 1, blended},
+		{1, 2, 2, blended},
+		{1, 3, 3, red},
+		// frame 1 is disposed to transparent black before frame 2
+		{2, 0, 0, green},
+		{2, 1, 1, Pixel{}},
+		{2, 3, 3, red},
+	}
+
+	for _, tt := range tests {
+		got := png.Frames[tt.frame].Image.Pixels[tt.y][tt.x]
+		if got != tt.expected {
+			t.Errorf("frame %d (%d, %d): expected %v, but got %v", tt.frame, tt.x, tt.y, tt.expected, got)
+		}
+	}
+}
+
+func TestReadAPNGSequence(t *testing.T) {
+	var file []byte
+	file = append(file, PNG_SIGNATURE...)
+	file = append(file, IHDRChunk(1, 1, 6)...)
+	file = append(file, ChunkBytes("acTL", []byte{0, 0, 0, 1, 0, 0, 0, 0})...)
+	file = append(file, fcTL(1, 1, 1, 0, 0, DISPOSE_NONE, BLEND_SOURCE)...)
+	file = append(file, IDATChunk(frameData(t, solid(1, 1, Pixel{})))...)
+	file = append(file, IENDChunk()...)
+
+	if _, err := ReadPNG(bytes.NewReader(file)); err == nil {
+		t.Fatal("expected an error for a sequence number starting at 1")
+	}
+}
@@ -0,0 +1,226 @@
+package exploring
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aaronbittel/color-picker/pngchunk"
+)
+
+var (
+	PNG_SIGNATURE = pngchunk.Signature
+)
+
+// decompress_zlib inflates data with the selected Inflater.
+func decompress_zlib(data []byte) ([]byte, error) {
+	return Inflater(data)
+}
+
+func inflateStd(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error: reading zlib data: %v", err)
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+type ChunkType [4]byte
+
+// IsCritical reports whether the chunk is needed to display the image,
+// which is marked by an uppercase first letter.
+func IsCritical(chunkType ChunkType) bool {
+	return chunkType[0]&0x20 == 0
+}
+
+func (c ChunkType) String() string {
+	return string(c[:])
+}
+
+type ColorType byte
+
+// channels is the number of samples per pixel.
+func (c ColorType) channels() int {
+	switch c {
+	case 0, 3:
+		return 1
+	case 4:
+		return 2
+	case 2:
+		return 3
+	case 6:
+		return 4
+	default:
+		return 0
+	}
+}
+
+func (c ColorType) String() string {
+	switch c {
+	case 0:
+		return "nothing"
+	case 2:
+		return "color"
+	case 3:
+		return "palette & color"
+	case 4:
+		return "alpha channel"
+	case 6:
+		return "color & alpha channel"
+	default:
+		panic(fmt.Sprintf("unknown colortype: %d", c))
+	}
+}
+
+type IHDR struct {
+	Chunk Chunk
+
+	Width             int
+	Height            int
+	BitDepth          byte
+	ColorType         ColorType
+	CompressionMethod byte
+	FilterMethod      byte
+	InterlaceMethod   byte
+}
+
+var allowedBitDepths = map[ColorType][]byte{
+	0: {1, 2, 4, 8, 16},
+	2: {8, 16},
+	3: {1, 2, 4, 8},
+	4: {8, 16},
+	6: {8, 16},
+}
+
+func (ihdr IHDR) validate() error {
+	depths, ok := allowedBitDepths[ihdr.ColorType]
+	if !ok {
+		return fmt.Errorf("error: unknown color type %d", ihdr.ColorType)
+	}
+
+	if !slices.Contains(depths, ihdr.BitDepth) {
+		return fmt.Errorf("error: bit depth %d is not allowed for color type %d",
+			ihdr.BitDepth, ihdr.ColorType)
+	}
+
+	if ihdr.CompressionMethod != 0 {
+		return fmt.Errorf("error: compression method 0 (deflate/inflate) is the only supported compression method")
+	}
+
+	if ihdr.FilterMethod != 0 {
+		return fmt.Errorf("error: filter method 0 is the only supported filter method")
+	}
+
+	if ihdr.InterlaceMethod != 0 && ihdr.InterlaceMethod != 1 {
+		return fmt.Errorf("error: interlace methods 0 or 1 are the only supported interlace methods")
+	}
+
+	if ihdr.Width <= 0 || ihdr.Height <= 0 {
+		return fmt.Errorf("error: invalid dimensions %dx%d", ihdr.Width, ihdr.Height)
+	}
+
+	return nil
+}
+
+// bytesPerPixel is the distance in bytes to the corresponding byte of the
+// previous pixel, as used by the filters.
+func (ihdr IHDR) bytesPerPixel() int {
+	return max(1, ihdr.ColorType.channels()*int(ihdr.BitDepth)/8)
+}
+
+// rowSize is the number of bytes of a scanline with the given width,
+// without the filter type byte.
+func (ihdr IHDR) rowSize(width int) int {
+	return (width*ihdr.ColorType.channels()*int(ihdr.BitDepth) + 7) / 8
+}
+
+func (ihdr IHDR) String() string {
+	sb := strings.Builder{}
+
+	sb.WriteString(fmt.Sprintf("\n\tPNG width: %d, height: %d\n", ihdr.Width, ihdr.Height))
+	sb.WriteString(fmt.Sprintf("\tbit depth: %d\n", ihdr.BitDepth))
+	sb.WriteString(fmt.Sprintf("\tcolor type: %s\n", ihdr.ColorType))
+	sb.WriteString(fmt.Sprintf("\tcompression method: %d\n", ihdr.CompressionMethod))
+	sb.WriteString(fmt.Sprintf("\tfilter method: %d\n", ihdr.FilterMethod))
+	sb.WriteString(fmt.Sprintf("\tinterlace method: %d\n", ihdr.InterlaceMethod))
+
+	return sb.String()
+}
+
+type Chunk struct {
+	Size      uint32
+	ChunkType ChunkType
+	Data      []byte
+	CRC       uint32
+}
+
+// read_chunk reads one chunk with pngchunk, which checks the length and crc.
+func read_chunk(r io.Reader) (Chunk, error) {
+	c, err := pngchunk.Read(r)
+	if err != nil {
+		return Chunk{}, err
+	}
+
+	var chunkType ChunkType
+	copy(chunkType[:], c.Type)
+
+	return Chunk{
+		Size:      uint32(len(c.Data)),
+		ChunkType: chunkType,
+		Data:      c.Data,
+		CRC:       c.CRC(),
+	}, nil
+}
+
+func read_signature(r io.Reader) error {
+	signature, err := read_n_bytes(r, 8)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Equal(PNG_SIGNATURE, signature) {
+		return fmt.Errorf("error: expected png signature %v, got %v", PNG_SIGNATURE, signature)
+	}
+
+	return nil
+}
+
+func read_n_bytes(r io.Reader, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+func NewIHDR(chunk Chunk) IHDR {
+	if chunk.ChunkType.String() != "IHDR" {
+		panic(fmt.Sprintf("error: trying to convert chunk to IHDR, but type is %v (%s)",
+			chunk.ChunkType, chunk.ChunkType))
+	}
+
+	if chunk.Size != 13 {
+		panic(fmt.Sprintf(
+			"error: trying to convert chunk to IHDR. Size is expected to be 13, but got %d",
+			chunk.Size))
+	}
+
+	return IHDR{
+		Chunk: chunk,
+
+		Width:             int(binary.BigEndian.Uint32(chunk.Data[:4])),
+		Height:            int(binary.BigEndian.Uint32(chunk.Data[4:8])),
+		BitDepth:          chunk.Data[8],
+		ColorType:         ColorType(chunk.Data[9]),
+		CompressionMethod: chunk.Data[10],
+		FilterMethod:      chunk.Data[11],
+		InterlaceMethod:   chunk.Data[12],
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+// corpus writes the test images of exploring.Corpus, to be checked with
+// verify.
+func corpus(args []string) error {
+	var dir string
+	fs := flag.NewFlagSet("corpus", flag.ExitOnError)
+	fs.StringVar(&dir, "o", "corpus", "directory the images are written to")
+	fs.Parse(args)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating directory (%s): %v", dir, err)
+	}
+
+	files := exploring.Corpus()
+	for _, file := range files {
+		var buf bytes.Buffer
+		if err := file.Encoder.Encode(&buf, file.Image); err != nil {
+			return fmt.Errorf("error encoding %s: %v", file.Name, err)
+		}
+
+		path := filepath.Join(dir, file.Name)
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("error writing file (%s): %v", path, err)
+		}
+	}
+
+	fmt.Printf("wrote %d images to %s\n", len(files), dir)
+	return nil
+}
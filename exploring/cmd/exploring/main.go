@@ -0,0 +1,266 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+const (
+	FullBlock = "█"
+	Reset     = "\033[m"
+)
+
+var COMMANDS = []string{"inspect", "palette", "frames", "optimize", "verify", "corpus"}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "error: no command given\nUse one of %s\n",
+			strings.Join(COMMANDS, ", "))
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "inspect":
+		err = inspect(os.Args[2:])
+	case "palette":
+		err = palette(os.Args[2:])
+	case "frames":
+		err = frames(os.Args[2:])
+	case "optimize":
+		err = optimize(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	case "corpus":
+		err = corpus(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s\nUse one of %s\n",
+			os.Args[1], strings.Join(COMMANDS, ", "))
+		os.Exit(1)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// fileArg returns the single png file given after the flags of a command.
+func fileArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 || path.Ext(fs.Arg(0)) != ".png" {
+		return "", fmt.Errorf("error: no png file given\nUSAGE: exploring %s [flags] <png-file>", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
+
+func readPNGFile(filepath string) (*exploring.PNG, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file (%s): %v", filepath, err)
+	}
+	defer f.Close()
+
+	return exploring.ReadPNG(f)
+}
+
+// inflateFlag adds the -inflate flag selecting the zlib decoder.
+func inflateFlag(fs *flag.FlagSet) *string {
+	return fs.String("inflate", "zlib", "zlib decoder, zlib (compress/zlib) or native")
+}
+
+func setInflater(name string) error {
+	inflater, ok := exploring.INFLATERS[name]
+	if !ok {
+		return fmt.Errorf("error: unknown inflater %q, use zlib or native", name)
+	}
+	exploring.Inflater = inflater
+	return nil
+}
+
+func inspect(args []string) error {
+	var (
+		blocks, trace bool
+		heatmap       string
+	)
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	inflater := inflateFlag(fs)
+	fs.BoolVar(&blocks, "blocks", false, "print the deflate blocks of the image data, implies -inflate native")
+	fs.BoolVar(&trace, "trace", false, "print the filter and compressed size of every scanline")
+	fs.StringVar(&heatmap, "heatmap", "", "filter heatmap written with -trace (default <file>.heatmap.png)")
+	fs.Parse(args)
+
+	filepath, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+
+	if blocks {
+		*inflater = "native"
+		exploring.InflateTrace = os.Stdout
+	}
+	if err := setInflater(*inflater); err != nil {
+		return err
+	}
+
+	png, err := readPNGFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	log.Println(png.IHDR)
+	for _, chunk := range png.Chunks {
+		log.Printf("read %s chunk (%d bytes)\n", chunk.ChunkType, chunk.Size)
+	}
+	for _, splt := range png.Suggested {
+		log.Printf("suggested palette %q with %d entries\n", splt.Name, len(splt.Entries))
+	}
+	if png.Animation != nil {
+		log.Printf("animation with %d frames, %d plays\n",
+			png.Animation.NumFrames, png.Animation.NumPlays)
+	}
+	log.Printf("decoded %dx%d pixels\n", png.Image.Width, png.Image.Height)
+
+	if !trace {
+		return nil
+	}
+
+	rows, err := traceRows(png)
+	if err != nil {
+		return err
+	}
+	printTrace(rows)
+
+	if heatmap == "" {
+		heatmap = strings.TrimSuffix(filepath, ".png") + ".heatmap.png"
+	}
+	if err := writeHeatmap(heatmap, png, rows); err != nil {
+		return err
+	}
+	fmt.Printf("wrote heatmap to %s\n", heatmap)
+
+	return nil
+}
+
+func frames(args []string) error {
+	var limit int
+	fs := flag.NewFlagSet("frames", flag.ExitOnError)
+	fs.IntVar(&limit, "limit", 8, "number of colors shown per frame")
+	fs.Parse(args)
+
+	filepath, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+
+	png, err := readPNGFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	if png.Animation == nil {
+		return fmt.Errorf("error: %s is not an animated png", filepath)
+	}
+
+	for i, frame := range png.Frames {
+		fc := frame.Control
+		fmt.Printf("frame %d: %dx%d at (%d, %d), %.3fs, dispose %s, blend %s\n",
+			i, fc.Width, fc.Height, fc.XOffset, fc.YOffset, fc.Delay(), fc.Dispose, fc.Blend)
+
+		colors := countColors(frame.Image)
+		fmt.Printf("  %d unique colors\n", len(colors))
+		for _, cc := range colors[:min(limit, len(colors))] {
+			fmt.Printf("  %s | %d pixels\n", formatPixel(cc.pixel), cc.count)
+		}
+	}
+
+	return nil
+}
+
+type PixelCount struct {
+	pixel exploring.Pixel
+	count int
+}
+
+// countColors returns the colors of the image, most frequent first.
+func countColors(img exploring.Image) []PixelCount {
+	counts := make(map[exploring.Pixel]int)
+	for _, row := range img.Pixels {
+		for _, p := range row {
+			counts[p]++
+		}
+	}
+
+	colors := make([]PixelCount, 0, len(counts))
+	for p, count := range counts {
+		colors = append(colors, PixelCount{p, count})
+	}
+	slices.SortFunc(colors, func(a, b PixelCount) int {
+		return cmp.Or(
+			cmp.Compare(b.count, a.count),
+			cmp.Compare(a.pixel.Red, b.pixel.Red),
+			cmp.Compare(a.pixel.Green, b.pixel.Green),
+			cmp.Compare(a.pixel.Blue, b.pixel.Blue),
+			cmp.Compare(a.pixel.Alpha, b.pixel.Alpha),
+		)
+	})
+
+	return colors
+}
+
+func palette(args []string) error {
+	fs := flag.NewFlagSet("palette", flag.ExitOnError)
+	fs.Parse(args)
+
+	filepath, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+
+	png, err := readPNGFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	return printPalette(png)
+}
+
+func printPalette(png *exploring.PNG) error {
+	if png.Palette == nil && png.Suggested == nil {
+		return fmt.Errorf("error: image has neither a PLTE nor an sPLT chunk")
+	}
+
+	if png.Palette != nil {
+		fmt.Printf("PLTE (%d entries)\n", len(png.Palette))
+		for i, p := range png.Palette {
+			fmt.Printf("%3d %s\n", i, formatPixel(p))
+		}
+	}
+
+	for _, splt := range png.Suggested {
+		fmt.Printf("sPLT %q (sample depth %d, %d entries)\n",
+			splt.Name, splt.SampleDepth, len(splt.Entries))
+		for i, entry := range splt.Entries {
+			fmt.Printf("%3d %s | frequency %5d\n", i, formatPixel(entry.Color), entry.Frequency)
+		}
+	}
+
+	return nil
+}
+
+func formatPixel(p exploring.Pixel) string {
+	red, green, blue, alpha := p.Red>>8, p.Green>>8, p.Blue>>8, p.Alpha>>8
+	return fmt.Sprintf("%s%s%s %3d-%3d-%3d | #%02X%02X%02X | alpha %3d",
+		colored(p), strings.Repeat(FullBlock, 5), Reset,
+		red, green, blue, red, green, blue, alpha)
+}
+
+func colored(p exploring.Pixel) string {
+	return fmt.Sprintf("\033[38;2;%d;%d;%dm", p.Red>>8, p.Green>>8, p.Blue>>8)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+// paletteIndexChunks refer to the entries of the palette, which the encoder
+// builds anew, so they are dropped when indexed images are re-encoded.
+var paletteIndexChunks = []string{"tRNS", "bKGD", "hIST"}
+
+// keptChunks change what the image shows and survive "-strip all".
+var keptChunks = []string{"tRNS", "acTL", "fcTL", "fdAT"}
+
+func optimize(args []string) error {
+	var (
+		strategies []string
+		out        string
+		filter     string
+		level      int
+		strip      string
+	)
+
+	for name := range exploring.FILTER_STRATEGIES {
+		strategies = append(strategies, name)
+	}
+	slices.Sort(strategies)
+
+	fs := flag.NewFlagSet("optimize", flag.ExitOnError)
+	fs.StringVar(&filter, "filter", "adaptive",
+		fmt.Sprintf("filter strategy, one of: %s", strings.Join(strategies, ", ")))
+	fs.IntVar(&level, "level", zlib.BestCompression, "zlib compression level from 0 to 9")
+	fs.StringVar(&strip, "strip", "",
+		"comma separated ancillary chunk types to drop, \"all\" drops every one except tRNS and animation chunks")
+	fs.StringVar(&out, "o", "", "output file (default <file>.optimized.png)")
+	fs.Parse(args)
+
+	filepath, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = strings.TrimSuffix(filepath, ".png") + ".optimized.png"
+	}
+
+	strategy, ok := exploring.FILTER_STRATEGIES[filter]
+	if !ok {
+		return fmt.Errorf("error: unknown filter strategy %s", filter)
+	}
+	if level < zlib.NoCompression || level > zlib.BestCompression {
+		return fmt.Errorf("error: zlib level must be between 0 and 9, got %d", level)
+	}
+
+	original, err := os.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	result, reencoded, err := optimizePNG(original, strategy, level, strip)
+	if err != nil {
+		return err
+	}
+	method := "original image data"
+	if reencoded {
+		method = fmt.Sprintf("re-encoded with %s filter, level %d", filter, level)
+	}
+
+	if err := os.WriteFile(out, result, 0o644); err != nil {
+		return err
+	}
+
+	saved := 100 * (1 - float64(len(result))/float64(len(original)))
+	fmt.Printf("before: %d bytes\n", len(original))
+	fmt.Printf("after:  %d bytes (%s)\n", len(result), method)
+	fmt.Printf("saved:  %.1f%% -> %s\n", saved, out)
+
+	return nil
+}
+
+// optimizePNG returns the smaller of the original image data without the
+// stripped chunks and the pixels re-encoded in the same color type, bit
+// depth and interlace method, and whether the re-encoded one won.
+func optimizePNG(original []byte, strategy exploring.FilterStrategy, level int, strip string) ([]byte, bool, error) {
+	png, err := exploring.ReadPNG(bytes.NewReader(original))
+	if err != nil {
+		return nil, false, err
+	}
+	indexed := png.IHDR.ColorType == 3
+
+	// Re-encoding can still make an image larger, so the original data with
+	// the chunks removed competes.
+	var (
+		copied         bytes.Buffer
+		reencodeChunks []exploring.Chunk
+	)
+	copied.Write(exploring.PNG_SIGNATURE)
+	for _, c := range png.Chunks {
+		chunkType := c.ChunkType.String()
+		if exploring.IsCritical(c.ChunkType) {
+			copied.Write(exploring.ChunkBytes(chunkType, c.Data))
+			continue
+		}
+		if stripped(chunkType, strip) {
+			continue
+		}
+		copied.Write(exploring.ChunkBytes(chunkType, c.Data))
+		if !indexed || !slices.Contains(paletteIndexChunks, chunkType) {
+			reencodeChunks = append(reencodeChunks, c)
+		}
+	}
+
+	result, reencoded := copied.Bytes(), false
+
+	// The encoder only writes a single image, re-encoding an animation
+	// would lose all frames but the default image.
+	if png.Animation == nil {
+		var buf bytes.Buffer
+		encoder := exploring.Encoder{
+			Filter:    strategy,
+			Level:     level,
+			Chunks:    reencodeChunks,
+			ColorType: png.IHDR.ColorType,
+			BitDepth:  png.IHDR.BitDepth,
+			Interlace: png.IHDR.InterlaceMethod == 1,
+		}
+		if err := encoder.Encode(&buf, png.Image); err != nil {
+			return nil, false, err
+		}
+
+		if buf.Len() < copied.Len() {
+			result, reencoded = buf.Bytes(), true
+		}
+	}
+
+	return result, reencoded, nil
+}
+
+func stripped(chunkType, strip string) bool {
+	if strip == "all" {
+		return !slices.Contains(keptChunks, chunkType)
+	}
+	return slices.Contains(strings.Split(strip, ","), chunkType)
+}
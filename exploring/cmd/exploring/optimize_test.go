@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+func TestOptimizeLossless(t *testing.T) {
+	for _, file := range exploring.Corpus() {
+		t.Run(file.Name, func(t *testing.T) {
+			// uncompressed, so the re-encoded image is the smaller one
+			encoder := file.Encoder
+			encoder.Level = zlib.NoCompression
+			var original bytes.Buffer
+			if err := encoder.Encode(&original, file.Image); err != nil {
+				t.Fatal(err)
+			}
+
+			optimized, reencoded, err := optimizePNG(original.Bytes(), exploring.ADAPTIVE, zlib.BestCompression, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reencoded && len(original.Bytes()) > 200 {
+				t.Errorf("expected the uncompressed image to be re-encoded")
+			}
+
+			before, err := exploring.ReadPNG(bytes.NewReader(original.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := exploring.ReadPNG(bytes.NewReader(optimized))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if after.IHDR.ColorType != before.IHDR.ColorType || after.IHDR.BitDepth != before.IHDR.BitDepth ||
+				after.IHDR.InterlaceMethod != before.IHDR.InterlaceMethod {
+				t.Fatalf("expected %s, but got %s", before.IHDR, after.IHDR)
+			}
+			for y, row := range before.Image.Pixels {
+				for x, p := range row {
+					if got := after.Image.Pixels[y][x]; got != p {
+						t.Fatalf("pixel (%d, %d): expected %v, but got %v", x, y, p, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOptimizeKeepsTransparentColor(t *testing.T) {
+	img := exploring.NewImage(16, 16)
+	img.Fill(exploring.RGB{Red: 10, Green: 20, Blue: 30})
+	img.Pixels[0][0] = exploring.Pixel{Red: 0xffff, Alpha: 0xffff}
+
+	// tRNS makes the red pixel transparent
+	trns := exploring.Chunk{ChunkType: exploring.ChunkType{'t', 'R', 'N', 'S'}, Data: []byte{0, 0xff, 0, 0, 0, 0}}
+	encoder := exploring.Encoder{Filter: exploring.FILTER_NONE, Level: zlib.NoCompression, ColorType: 2, BitDepth: 8, Chunks: []exploring.Chunk{trns}}
+	var original bytes.Buffer
+	if err := encoder.Encode(&original, img); err != nil {
+		t.Fatal(err)
+	}
+
+	optimized, _, err := optimizePNG(original.Bytes(), exploring.ADAPTIVE, zlib.BestCompression, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := exploring.ReadPNG(bytes.NewReader(optimized))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := after.Image.Pixels[0][0]; p.Alpha != 0 {
+		t.Errorf("expected the red pixel to stay transparent, but got %v", p)
+	}
+}
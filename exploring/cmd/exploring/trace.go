@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+// heatmapWidth is the width in cells of a scanline whose compressed size
+// equals its raw size.
+const heatmapWidth = 40
+
+// FILTER_COLORS mark the filter of a scanline in the heatmaps.
+var FILTER_COLORS = map[exploring.Filter]exploring.Pixel{
+	exploring.NONE:    {Red: 0x8080, Green: 0x8080, Blue: 0x8080, Alpha: 0xffff},
+	exploring.SUB:     {Red: 0xe0e0, Green: 0x4040, Blue: 0x4040, Alpha: 0xffff},
+	exploring.UP:      {Red: 0x4040, Green: 0xc0c0, Blue: 0x4040, Alpha: 0xffff},
+	exploring.AVERAGE: {Red: 0x4040, Green: 0x6060, Blue: 0xe0e0, Alpha: 0xffff},
+	exploring.PAETH:   {Red: 0xe0e0, Green: 0xc0c0, Blue: 0x2020, Alpha: 0xffff},
+}
+
+// RowTrace is a scanline with the number of compressed bits it took.
+type RowTrace struct {
+	exploring.Scanline
+	Bits int
+}
+
+// Ratio is the compressed size relative to the raw size of the row.
+func (r RowTrace) Ratio() float64 {
+	return float64(r.Bits) / 8 / float64(r.Size)
+}
+
+// traceRows attributes the compressed image data to the scanlines of the
+// default image. Headers of the zlib stream and of deflate blocks count
+// towards the row that follows them.
+func traceRows(png *exploring.PNG) ([]RowTrace, error) {
+	var idat []byte
+	for _, chunk := range png.Chunks {
+		if chunk.ChunkType.String() == "IDAT" {
+			idat = append(idat, chunk.Data...)
+		}
+	}
+
+	_, ends, err := exploring.InflatePositions(idat)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]RowTrace, 0, len(png.Scanlines))
+	for _, s := range png.Scanlines {
+		start := 0
+		if s.Offset > 0 {
+			start = ends[s.Offset-1]
+		}
+		rows = append(rows, RowTrace{s, ends[s.Offset+s.Size-1] - start})
+	}
+
+	return rows, nil
+}
+
+func printTrace(rows []RowTrace) {
+	fmt.Println("  row pass filter     raw  compressed")
+
+	counts := map[exploring.Filter]int{}
+	raw, compressed := 0, 0
+	for _, r := range rows {
+		counts[r.Filter]++
+		raw += r.Size
+		compressed += r.Bits
+
+		bar := max(1, min(heatmapWidth, int(r.Ratio()*heatmapWidth+0.5)))
+		fmt.Printf("%5d %4d %-7s %6d %11.1f %s%s%s\n",
+			r.Y, r.Pass, r.Filter, r.Size, float64(r.Bits)/8,
+			colored(FILTER_COLORS[r.Filter]), strings.Repeat(FullBlock, bar), Reset)
+	}
+
+	fmt.Printf("%d scanlines, %d raw bytes, %.1f compressed bytes (%.1f%%)\n",
+		len(rows), raw, float64(compressed)/8, float64(compressed)/8/float64(raw)*100)
+	for f := exploring.NONE; f <= exploring.PAETH; f++ {
+		fmt.Printf("%s%s%s %-7s %d rows\n",
+			colored(FILTER_COLORS[f]), FullBlock, Reset, f, counts[f])
+	}
+}
+
+// writeHeatmap paints every pixel in the color of the filter of its
+// scanline, darker where the row compresses well.
+func writeHeatmap(path string, png *exploring.PNG, rows []RowTrace) error {
+	img := exploring.NewImage(png.Image.Width, png.Image.Height)
+
+	for _, r := range rows {
+		c := FILTER_COLORS[r.Filter]
+		shade := 0.3 + 0.7*min(r.Ratio(), 1)
+		c.Red = uint16(float64(c.Red) * shade)
+		c.Green = uint16(float64(c.Green) * shade)
+		c.Blue = uint16(float64(c.Blue) * shade)
+
+		for x := r.X; x < int(img.Width); x += r.Step {
+			img.Pixels[r.Y][x] = c
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file (%s): %v", path, err)
+	}
+
+	if err := exploring.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+func TestTraceRows(t *testing.T) {
+	img := exploring.NewImage(8, 6)
+	for y := range img.Pixels {
+		for x := range img.Pixels[y] {
+			img.Pixels[y][x] = exploring.Pixel{Red: uint16(x * 0x2000), Green: uint16(y * 0x2000), Alpha: 0xffff}
+		}
+	}
+
+	// Besides the rows the zlib stream holds its 2 byte header and 4 byte
+	// checksum. Compressed data also ends with the end of block code, up to
+	// 15 bits, and the padding to a whole byte.
+	tests := []struct {
+		level int
+		slack int
+	}{
+		{zlib.NoCompression, 0},
+		{zlib.BestCompression, 15 + 7},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		encoder := exploring.Encoder{Filter: exploring.FILTER_NONE, Level: tt.level, ColorType: 2, BitDepth: 8}
+		if err := encoder.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		png, err := exploring.ReadPNG(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := traceRows(png)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 6 {
+			t.Fatalf("level %d: expected 6 rows, but got %d", tt.level, len(rows))
+		}
+
+		idat := 0
+		for _, c := range png.Chunks {
+			if c.ChunkType.String() == "IDAT" {
+				idat += len(c.Data)
+			}
+		}
+		bits := 0
+		for _, r := range rows {
+			if r.Bits <= 0 {
+				t.Errorf("level %d: row %d took %d bits", tt.level, r.Y, r.Bits)
+			}
+			bits += r.Bits
+		}
+		if rest := 8*(idat-6) - bits; rest < 0 || rest > tt.slack {
+			t.Errorf("level %d: expected the rows to take %d bits of the IDAT data, up to %d less, but got %d",
+				tt.level, 8*(idat-6), tt.slack, bits)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+// verify decodes every file with exploring and image/png and reports the
+// first pixel they disagree on.
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	inflater := inflateFlag(fs)
+	fs.Parse(args)
+
+	if err := setInflater(*inflater); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("error: no png files given\nUSAGE: exploring verify [flags] <png-file|directory>...")
+	}
+
+	files, err := pngFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, file := range files {
+		if msg := verifyFile(file); msg != "" {
+			failed++
+			fmt.Printf("FAIL %s\n%s", file, msg)
+		} else {
+			fmt.Printf("PASS %s\n", file)
+		}
+	}
+
+	fmt.Printf("%d of %d files passed\n", len(files)-failed, len(files))
+	if failed > 0 {
+		return fmt.Errorf("error: %d files differ from image/png", failed)
+	}
+	return nil
+}
+
+// pngFiles expands directories into the png files inside them.
+func pngFiles(args []string) ([]string, error) {
+	var files []string
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file (%s): %v", arg, err)
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		err = filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.EqualFold(filepath.Ext(path), ".png") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading directory (%s): %v", arg, err)
+		}
+	}
+
+	return files, nil
+}
+
+// verifyFile returns a description of the mismatch, or an empty string if
+// both decoders agree.
+func verifyFile(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Sprintf("  %v\n", err)
+	}
+
+	native, nativeErr := exploring.ReadPNG(bytes.NewReader(data))
+	std, stdErr := png.Decode(bytes.NewReader(data))
+
+	switch {
+	case nativeErr != nil && stdErr != nil:
+		// both reject the file, which is what broken files are for
+		return ""
+	case nativeErr != nil:
+		return fmt.Sprintf("  exploring: %v\n", nativeErr)
+	case stdErr != nil:
+		return fmt.Sprintf("  image/png: %v\n", stdErr)
+	}
+
+	bounds := std.Bounds()
+	if bounds.Dx() != int(native.Image.Width) || bounds.Dy() != int(native.Image.Height) {
+		return fmt.Sprintf("  size: exploring %dx%d, image/png %dx%d\n",
+			native.Image.Width, native.Image.Height, bounds.Dx(), bounds.Dy())
+	}
+
+	for y := range bounds.Dy() {
+		for x := range bounds.Dx() {
+			want := nrgba64(std.At(bounds.Min.X+x, bounds.Min.Y+y))
+			got := native.Image.Pixels[y][x]
+			if got.Alpha == 0 && want.A == 0 {
+				continue
+			}
+			if got != (exploring.Pixel{Red: want.R, Green: want.G, Blue: want.B, Alpha: want.A}) {
+				return fmt.Sprintf("  pixel (%d, %d): exploring %v, image/png %v\n%s",
+					x, y, got, want, rowContext(native, x, y))
+			}
+		}
+	}
+
+	return ""
+}
+
+// rowContext describes the header, the image data chunks and the scanline a
+// pixel was decoded from.
+func rowContext(png *exploring.PNG, x, y int) string {
+	var b strings.Builder
+	ihdr := png.IHDR
+
+	fmt.Fprintf(&b, "  color type %d, bit depth %d, interlace %d\n",
+		ihdr.ColorType, ihdr.BitDepth, ihdr.InterlaceMethod)
+
+	var sizes []string
+	for _, chunk := range png.Chunks {
+		if chunk.ChunkType.String() == "IDAT" {
+			sizes = append(sizes, fmt.Sprint(chunk.Size))
+		}
+	}
+	fmt.Fprintf(&b, "  %d IDAT chunks (%s bytes)\n", len(sizes), strings.Join(sizes, ", "))
+
+	if s, ok := png.ScanlineAt(x, y); ok {
+		fmt.Fprintf(&b, "  row %d: pass %d, filter %s, %d bytes at offset %d of the inflated data\n",
+			y, s.Pass, s.Filter, s.Size, s.Offset)
+	}
+
+	return b.String()
+}
+
+// nrgba64 converts without going through premultiplied alpha, which would
+// lose precision for translucent colors.
+func nrgba64(c color.Color) color.NRGBA64 {
+	if n, ok := c.(color.NRGBA); ok {
+		return color.NRGBA64{uint16(n.R) * 257, uint16(n.G) * 257, uint16(n.B) * 257, uint16(n.A) * 257}
+	}
+	return color.NRGBA64Model.Convert(c).(color.NRGBA64)
+}
@@ -0,0 +1,102 @@
+package exploring
+
+import (
+	"fmt"
+)
+
+// The corpus mirrors the naming of PngSuite: a prefix, n or i for
+// non-interlaced or interlaced, the color type, a letter for it and the bit
+// depth. bas files use the adaptive filter, f00 to f04 a single filter for
+// every row and s01 to s09 are images of that size in pixels.
+
+var colorTypeLetters = map[ColorType]byte{0: 'g', 2: 'c', 3: 'p', 4: 'a', 6: 'a'}
+
+// CorpusFile is an image together with the encoder settings to write it.
+type CorpusFile struct {
+	Name    string
+	Encoder Encoder
+	Image   Image
+}
+
+// Corpus returns test images in every combination of color type, bit
+// depth, filter and interlace method.
+func Corpus() []CorpusFile {
+	var files []CorpusFile
+
+	for _, interlace := range []bool{false, true} {
+		mode := byte('n')
+		if interlace {
+			mode = 'i'
+		}
+
+		for _, colorType := range []ColorType{0, 2, 3, 4, 6} {
+			for _, depth := range allowedBitDepths[colorType] {
+				format := fmt.Sprintf("%c%d%c%02d", mode, colorType, colorTypeLetters[colorType], depth)
+				img := corpusImage(32, 32, colorType, depth)
+
+				files = append(files, CorpusFile{
+					Name:    "bas" + format,
+					Encoder: Encoder{Filter: ADAPTIVE, ColorType: colorType, BitDepth: depth, Interlace: interlace},
+					Image:   img,
+				})
+				for f := FILTER_NONE; f <= FILTER_PAETH; f++ {
+					files = append(files, CorpusFile{
+						Name:    fmt.Sprintf("f%02d%s", f, format),
+						Encoder: Encoder{Filter: f, ColorType: colorType, BitDepth: depth, Interlace: interlace},
+						Image:   img,
+					})
+				}
+			}
+		}
+
+		for size := 1; size <= 9; size++ {
+			files = append(files, CorpusFile{
+				Name:    fmt.Sprintf("s%02d%c2c08", size, mode),
+				Encoder: Encoder{Filter: ADAPTIVE, ColorType: 2, BitDepth: 8, Interlace: interlace},
+				Image:   corpusImage(uint32(size), uint32(size), 2, 8),
+			})
+		}
+	}
+
+	for i := range files {
+		files[i].Encoder.Level = 9
+		files[i].Name += ".png"
+	}
+
+	return files
+}
+
+// corpusImage draws gradients that use the full range of the bit depth.
+// Indexed images cycle through as many colors as the palette can hold, with
+// some of them translucent.
+func corpusImage(width, height uint32, colorType ColorType, depth byte) Image {
+	img := NewImage(width, height)
+
+	for y := range height {
+		for x := range width {
+			var p Pixel
+			if colorType == 3 {
+				i := (x + 3*y) % (1 << depth)
+				p = Pixel{
+					Red:   uint16(i*37%256) * 257,
+					Green: uint16(255-i*11%256) * 257,
+					Blue:  uint16(i*5%256) * 257,
+					Alpha: uint16(255-i%4*60) * 257,
+				}
+			} else {
+				p = Pixel{
+					Red:   uint16(x*2113 + y*97),
+					Green: uint16(y*2039 + x*31),
+					Blue:  uint16((x + y) * 1021),
+					Alpha: 0xffff,
+				}
+				if colorType == 4 || colorType == 6 {
+					p.Alpha = uint16(0xffff - x*y*63)
+				}
+			}
+			img.Pixels[y][x] = p
+		}
+	}
+
+	return img
+}
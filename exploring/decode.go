@@ -0,0 +1,409 @@
+package exploring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// PNG is a decoded png file. All chunks are kept in the order they appeared
+// in, the ones needed to reconstruct the pixels are parsed.
+type PNG struct {
+	IHDR   IHDR
+	Chunks []Chunk
+
+	// Palette holds the PLTE entries with the alpha values of tRNS applied.
+	Palette []Pixel
+	// transparent is the single transparent color of a tRNS chunk for
+	// grayscale and truecolor images.
+	transparent *Pixel
+	// Suggested holds the sPLT chunks.
+	Suggested []SuggestedPalette
+
+	// Animation is set for APNG files, Frames holds every animation frame
+	// composed onto the full canvas.
+	Animation *AnimationControl
+	Frames    []Frame
+	sequence  int
+
+	Image Image
+	// Scanlines describes the rows of the default image's data.
+	Scanlines []Scanline
+}
+
+// SuggestedPalette is the content of an sPLT chunk.
+type SuggestedPalette struct {
+	Name        string
+	SampleDepth byte
+	Entries     []SuggestedEntry
+}
+
+type SuggestedEntry struct {
+	Color     Pixel
+	Frequency uint16
+}
+
+func ReadPNG(r io.Reader) (*PNG, error) {
+	if err := read_signature(r); err != nil {
+		return nil, fmt.Errorf("error reading signature: %v", err)
+	}
+
+	ihdrChunk, err := read_chunk(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading IHDR chunk: %v", err)
+	}
+	if ihdrChunk.ChunkType.String() != "IHDR" || ihdrChunk.Size != 13 {
+		return nil, fmt.Errorf("error: expected a 13 byte IHDR chunk first, got %s with %d bytes",
+			ihdrChunk.ChunkType, ihdrChunk.Size)
+	}
+	ihdr := NewIHDR(ihdrChunk)
+	if err := ihdr.validate(); err != nil {
+		return nil, err
+	}
+
+	png := &PNG{IHDR: ihdr, Chunks: []Chunk{ihdrChunk}}
+
+	var idat []byte
+
+outer:
+	for {
+		chunk, err := read_chunk(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading chunk: %v", err)
+		}
+		png.Chunks = append(png.Chunks, chunk)
+
+		switch chunk.ChunkType.String() {
+		case "IEND":
+			break outer
+		case "PLTE":
+			if err := png.parsePLTE(chunk); err != nil {
+				return nil, err
+			}
+		case "tRNS":
+			if err := png.parseTRNS(chunk); err != nil {
+				return nil, err
+			}
+		case "sPLT":
+			splt, err := parseSPLT(chunk)
+			if err != nil {
+				return nil, err
+			}
+			png.Suggested = append(png.Suggested, splt)
+		case "acTL":
+			if err := png.parseACTL(chunk); err != nil {
+				return nil, err
+			}
+		case "fcTL":
+			control, err := png.parseFCTL(chunk)
+			if err != nil {
+				return nil, err
+			}
+			png.Frames = append(png.Frames, Frame{Control: control, IsDefault: idat == nil})
+		case "fdAT":
+			if len(png.Frames) == 0 || png.Frames[len(png.Frames)-1].IsDefault {
+				return nil, fmt.Errorf("error: fdAT chunk without a preceding fcTL chunk")
+			}
+			if err := png.checkSequence(chunk.Data); err != nil {
+				return nil, err
+			}
+			frame := &png.Frames[len(png.Frames)-1]
+			frame.Data = append(frame.Data, chunk.Data[4:]...)
+		case "IDAT":
+			idat = append(idat, chunk.Data...)
+		}
+	}
+
+	if ihdr.ColorType == 3 && png.Palette == nil {
+		return nil, fmt.Errorf("error: indexed color image without PLTE chunk")
+	}
+
+	png.Image, png.Scanlines, err = png.decodeImage(idat, ihdr.Width, ihdr.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := png.renderFrames(); err != nil {
+		return nil, err
+	}
+
+	return png, nil
+}
+
+// Scanline describes one row of the inflated image data.
+type Scanline struct {
+	// Pass is the interlace pass the row belongs to, always 0 without
+	// interlacing.
+	Pass int
+	// Y is the row of the image the scanline's pixels are in, starting at
+	// column X and Step pixels apart.
+	Y      int
+	X      int
+	Step   int
+	Filter Filter
+	// Offset is the position of the filter type byte in the inflated data,
+	// Size the length of the scanline including it.
+	Offset int
+	Size   int
+}
+
+// ScanlineAt returns the scanline of the default image that holds the pixel
+// at x, y.
+func (png *PNG) ScanlineAt(x, y int) (Scanline, bool) {
+	for _, s := range png.Scanlines {
+		if s.Y == y && x >= s.X && (x-s.X)%s.Step == 0 {
+			return s, true
+		}
+	}
+	return Scanline{}, false
+}
+
+// decodeImage inflates, unfilters and expands the image data of the default
+// image or of an animation frame. Interlaced data is deinterlaced pass by
+// pass.
+func (png *PNG) decodeImage(compressed []byte, width, height int) (Image, []Scanline, error) {
+	ihdr := png.IHDR
+
+	data, err := decompress_zlib(compressed)
+	if err != nil {
+		return Image{}, nil, err
+	}
+
+	// The dimensions come from the file, so they are checked against the
+	// data before allocating the image.
+	expected := 0
+	for _, p := range ihdr.passes() {
+		passWidth, passHeight := p.size(width, height)
+		if passWidth > 0 && passHeight > 0 {
+			expected += (ihdr.rowSize(passWidth) + 1) * passHeight
+		}
+	}
+	if len(data) < expected {
+		return Image{}, nil, fmt.Errorf("error: expected %d bytes of image data for %dx%d pixels, got %d",
+			expected, width, height, len(data))
+	}
+
+	img := NewImage(uint32(width), uint32(height))
+	var scanlines []Scanline
+	offset := 0
+
+	for i, p := range ihdr.passes() {
+		passWidth, passHeight := p.size(width, height)
+		if passWidth == 0 || passHeight == 0 {
+			continue
+		}
+
+		rowSize := ihdr.rowSize(passWidth)
+		unfilteredData, err := unfilter(data[offset:], rowSize, passHeight, ihdr.bytesPerPixel())
+		if err != nil {
+			return Image{}, nil, fmt.Errorf("error in pass %d: %v", i, err)
+		}
+
+		for row := range passHeight {
+			start := offset + row*(rowSize+1)
+			scanlines = append(scanlines, Scanline{
+				Pass:   i,
+				Y:      p.y + row*p.dy,
+				X:      p.x,
+				Step:   p.dx,
+				Filter: Filter(data[start]),
+				Offset: start,
+				Size:   rowSize + 1,
+			})
+		}
+		offset += (rowSize + 1) * passHeight
+
+		reduced := NewImage(uint32(passWidth), uint32(passHeight))
+		if err := png.expand(reduced, unfilteredData); err != nil {
+			return Image{}, nil, err
+		}
+		for y, row := range reduced.Pixels {
+			for x, pixel := range row {
+				img.Pixels[p.y+y*p.dy][p.x+x*p.dx] = pixel
+			}
+		}
+	}
+
+	return img, scanlines, nil
+}
+
+func (png *PNG) parsePLTE(chunk Chunk) error {
+	if png.IHDR.ColorType == 0 || png.IHDR.ColorType == 4 {
+		return fmt.Errorf("error: PLTE chunk is not allowed for color type %d", png.IHDR.ColorType)
+	}
+	if len(chunk.Data)%3 != 0 || len(chunk.Data) == 0 || len(chunk.Data) > 3*256 {
+		return fmt.Errorf("error: PLTE chunk has invalid length %d", len(chunk.Data))
+	}
+
+	png.Palette = make([]Pixel, len(chunk.Data)/3)
+	for i := range png.Palette {
+		png.Palette[i] = Pixel{
+			Red:   uint16(chunk.Data[3*i]) * 257,
+			Green: uint16(chunk.Data[3*i+1]) * 257,
+			Blue:  uint16(chunk.Data[3*i+2]) * 257,
+			Alpha: 0xffff,
+		}
+	}
+
+	return nil
+}
+
+func (png *PNG) parseTRNS(chunk Chunk) error {
+	data := chunk.Data
+
+	switch png.IHDR.ColorType {
+	case 0:
+		if len(data) != 2 {
+			return fmt.Errorf("error: tRNS chunk for grayscale must be 2 bytes, got %d", len(data))
+		}
+		gray := png.scale(binary.BigEndian.Uint16(data))
+		png.transparent = &Pixel{Red: gray, Green: gray, Blue: gray}
+	case 2:
+		if len(data) != 6 {
+			return fmt.Errorf("error: tRNS chunk for truecolor must be 6 bytes, got %d", len(data))
+		}
+		png.transparent = &Pixel{
+			Red:   png.scale(binary.BigEndian.Uint16(data[0:2])),
+			Green: png.scale(binary.BigEndian.Uint16(data[2:4])),
+			Blue:  png.scale(binary.BigEndian.Uint16(data[4:6])),
+		}
+	case 3:
+		if png.Palette == nil {
+			return fmt.Errorf("error: tRNS chunk before PLTE chunk")
+		}
+		if len(data) > len(png.Palette) {
+			return fmt.Errorf("error: tRNS chunk has %d entries, but the palette only %d",
+				len(data), len(png.Palette))
+		}
+		for i, alpha := range data {
+			png.Palette[i].Alpha = uint16(alpha) * 257
+		}
+	default:
+		return fmt.Errorf("error: tRNS chunk is not allowed for color type %d", png.IHDR.ColorType)
+	}
+
+	return nil
+}
+
+func parseSPLT(chunk Chunk) (SuggestedPalette, error) {
+	name, rest, ok := bytes.Cut(chunk.Data, []byte{0})
+	if !ok || len(name) == 0 || len(name) > 79 || len(rest) == 0 {
+		return SuggestedPalette{}, fmt.Errorf("error: malformed sPLT chunk")
+	}
+
+	splt := SuggestedPalette{Name: string(name), SampleDepth: rest[0]}
+	data := rest[1:]
+
+	var entrySize int
+	switch splt.SampleDepth {
+	case 8:
+		entrySize = 6
+	case 16:
+		entrySize = 10
+	default:
+		return SuggestedPalette{}, fmt.Errorf("error: sPLT sample depth must be 8 or 16, got %d",
+			splt.SampleDepth)
+	}
+	if len(data)%entrySize != 0 {
+		return SuggestedPalette{}, fmt.Errorf("error: sPLT data of %d bytes is not a multiple of %d",
+			len(data), entrySize)
+	}
+
+	for i := 0; i < len(data); i += entrySize {
+		entry := data[i : i+entrySize]
+		var p Pixel
+		if splt.SampleDepth == 8 {
+			p = Pixel{
+				Red:   uint16(entry[0]) * 257,
+				Green: uint16(entry[1]) * 257,
+				Blue:  uint16(entry[2]) * 257,
+				Alpha: uint16(entry[3]) * 257,
+			}
+		} else {
+			p = Pixel{
+				Red:   binary.BigEndian.Uint16(entry[0:2]),
+				Green: binary.BigEndian.Uint16(entry[2:4]),
+				Blue:  binary.BigEndian.Uint16(entry[4:6]),
+				Alpha: binary.BigEndian.Uint16(entry[6:8]),
+			}
+		}
+		splt.Entries = append(splt.Entries, SuggestedEntry{
+			Color:     p,
+			Frequency: binary.BigEndian.Uint16(entry[entrySize-2:]),
+		})
+	}
+
+	return splt, nil
+}
+
+// scale stretches a sample of the image's bit depth to 16 bits.
+func (png *PNG) scale(sample uint16) uint16 {
+	depth := png.IHDR.BitDepth
+	return uint16(uint32(sample) * 0xffff / (1<<depth - 1))
+}
+
+// samples splits a scanline into samples of the image's bit depth.
+func (png *PNG) samples(row []byte, n int) []uint16 {
+	depth := int(png.IHDR.BitDepth)
+	samples := make([]uint16, n)
+
+	for i := range samples {
+		switch depth {
+		case 16:
+			samples[i] = binary.BigEndian.Uint16(row[2*i:])
+		case 8:
+			samples[i] = uint16(row[i])
+		default:
+			bit := i * depth
+			shift := 8 - depth - bit%8
+			samples[i] = uint16(row[bit/8]>>shift) & (1<<depth - 1)
+		}
+	}
+
+	return samples
+}
+
+// expand turns the unfiltered scanlines into the pixels of img.
+func (png *PNG) expand(img Image, data []byte) error {
+	ihdr := png.IHDR
+	width, height := int(img.Width), int(img.Height)
+	rowSize := ihdr.rowSize(width)
+	channels := ihdr.ColorType.channels()
+
+	for y := range height {
+		row := data[y*rowSize : (y+1)*rowSize]
+		samples := png.samples(row, width*channels)
+
+		for x := range width {
+			s := samples[x*channels : (x+1)*channels]
+			var p Pixel
+
+			switch ihdr.ColorType {
+			case 0:
+				gray := png.scale(s[0])
+				p = Pixel{gray, gray, gray, 0xffff}
+			case 2:
+				p = Pixel{png.scale(s[0]), png.scale(s[1]), png.scale(s[2]), 0xffff}
+			case 3:
+				if int(s[0]) >= len(png.Palette) {
+					return fmt.Errorf("error: palette index %d out of range at (%d, %d)", s[0], x, y)
+				}
+				p = png.Palette[s[0]]
+			case 4:
+				gray := png.scale(s[0])
+				p = Pixel{gray, gray, gray, png.scale(s[1])}
+			case 6:
+				p = Pixel{png.scale(s[0]), png.scale(s[1]), png.scale(s[2]), png.scale(s[3])}
+			}
+
+			if t := png.transparent; t != nil && p == (Pixel{t.Red, t.Green, t.Blue, 0xffff}) {
+				p.Alpha = 0
+			}
+
+			img.Pixels[y][x] = p
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package exploring
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodeStd(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// nrgba64 converts without going through premultiplied alpha, which would
+// lose precision for translucent colors.
+func nrgba64(c color.Color) color.NRGBA64 {
+	switch c := c.(type) {
+	case color.NRGBA:
+		return color.NRGBA64{uint16(c.R) * 257, uint16(c.G) * 257, uint16(c.B) * 257, uint16(c.A) * 257}
+	case color.NRGBA64:
+		return c
+	default:
+		return color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	}
+}
+
+// comparePixels checks got against what image/png decodes from the same file.
+func comparePixels(t *testing.T, file []byte, got Image) {
+	t.Helper()
+	expected, err := png.Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for y := range int(got.Height) {
+		for x := range int(got.Width) {
+			want := nrgba64(expected.At(x, y))
+			p := got.Pixels[y][x]
+			if p.Alpha == 0 && want.A == 0 {
+				continue
+			}
+			if (Pixel{want.R, want.G, want.B, want.A}) != p {
+				t.Fatalf("pixel (%d, %d): expected %v, but got %v", x, y, want, p)
+			}
+		}
+	}
+}
+
+func TestReadPNGPaletted(t *testing.T) {
+	for _, size := range []int{2, 4, 16, 256} {
+		t.Run(fmt.Sprintf("%d colors", size), func(t *testing.T) {
+			palette := color.Palette{}
+			for i := range size {
+				palette = append(palette, color.NRGBA{
+					R: uint8(i * 7), G: uint8(255 - i), B: uint8(i * 3), A: uint8(255 - i%3*100),
+				})
+			}
+
+			img := image.NewPaletted(image.Rect(0, 0, 13, 7), palette)
+			for y := range 7 {
+				for x := range 13 {
+					img.SetColorIndex(x, y, uint8((x*y+x)%size))
+				}
+			}
+
+			file := encodeStd(t, img)
+			decoded, err := ReadPNG(bytes.NewReader(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded.Palette) != size {
+				t.Fatalf("expected %d palette entries, but got %d", size, len(decoded.Palette))
+			}
+			for i, p := range decoded.Palette {
+				want := nrgba64(palette[i])
+				if (Pixel{want.R, want.G, want.B, want.A}) != p {
+					t.Fatalf("palette entry %d: expected %v, but got %v", i, want, p)
+				}
+			}
+			comparePixels(t, file, decoded.Image)
+		})
+	}
+}
+
+func TestReadPNGColorTypes(t *testing.T) {
+	rect := image.Rect(0, 0, 9, 5)
+	images := map[string]image.Image{
+		"gray":    image.NewGray(rect),
+		"gray16":  image.NewGray16(rect),
+		"rgb":     image.NewRGBA(rect),
+		"nrgba":   image.NewNRGBA(rect),
+		"rgb16":   image.NewRGBA64(rect),
+		"nrgba64": image.NewNRGBA64(rect),
+	}
+
+	for name, img := range images {
+		t.Run(name, func(t *testing.T) {
+			set := img.(interface{ Set(x, y int, c color.Color) })
+			for y := range 5 {
+				for x := range 9 {
+					alpha := uint16(0xffff)
+					if name == "nrgba" || name == "nrgba64" {
+						alpha = uint16(x * 7000)
+					}
+					set.Set(x, y, color.NRGBA64{
+						R: uint16(x * 7001), G: uint16(y * 13001), B: uint16(x*y*999 + 5), A: alpha,
+					})
+				}
+			}
+
+			file := encodeStd(t, img)
+			decoded, err := ReadPNG(bytes.NewReader(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			comparePixels(t, file, decoded.Image)
+		})
+	}
+}
+
+func TestParseSPLT(t *testing.T) {
+	data := []byte("flat\x00\x10")
+	data = append(data, 0xff, 0xff, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x01, 0x00)
+	data = append(data, 0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0x00, 0x00, 0x07)
+
+	splt, err := parseSPLT(Chunk{Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SuggestedEntry{
+		{Pixel{0xffff, 0x8000, 0, 0xffff}, 256},
+		{Pixel{1, 2, 3, 0}, 7},
+	}
+	if splt.Name != "flat" || splt.SampleDepth != 16 || len(splt.Entries) != len(expected) {
+		t.Fatalf("unexpected palette %+v", splt)
+	}
+	for i, entry := range expected {
+		if splt.Entries[i] != entry {
+			t.Errorf("entry %d: expected %+v, but got %+v", i, entry, splt.Entries[i])
+		}
+	}
+
+	if _, err := parseSPLT(Chunk{Data: []byte("flat\x00\x08\x01\x02")}); err == nil {
+		t.Errorf("expected an error for a truncated entry")
+	}
+}
+
+// a header claiming 65535x65535 pixels must not allocate them for a few
+// bytes of image data
+func TestReadPNGTruncatedData(t *testing.T) {
+	idat, err := compress_zlib([]byte{0, 1, 2, 3}, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var file []byte
+	file = append(file, PNG_SIGNATURE...)
+	file = append(file, IHDR{Width: 65535, Height: 65535, BitDepth: 8, ColorType: 6}.Bytes()...)
+	file = append(file, IDATChunk(idat)...)
+	file = append(file, IENDChunk()...)
+
+	allocs := testing.AllocsPerRun(1, func() {
+		if _, err := ReadPNG(bytes.NewReader(file)); err == nil {
+			t.Error("expected an error for missing image data")
+		}
+	})
+	if allocs > 1000 {
+		t.Errorf("expected few allocations, but got %.0f", allocs)
+	}
+}
@@ -0,0 +1,252 @@
+package exploring
+
+import (
+	"fmt"
+)
+
+type Filter byte
+
+const (
+	NONE Filter = iota
+	SUB
+	UP
+	AVERAGE
+	PAETH
+)
+
+func (f Filter) String() string {
+	switch f {
+	case NONE:
+		return "none"
+	case SUB:
+		return "sub"
+	case UP:
+		return "up"
+	case AVERAGE:
+		return "average"
+	case PAETH:
+		return "paeth"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(f))
+	}
+}
+
+// The filters work on bytes, not pixels. bpp is the number of bytes per
+// complete pixel, rounded up to one, and decides which byte is "left".
+
+func subFilter(data []byte, bpp int) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		row = append(row, data[i]-get_or(data, i-bpp, 0))
+	}
+	return row
+}
+
+func unSubFilter(data []byte, bpp int) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		row = append(row, data[i]+get_or(row, i-bpp, 0))
+	}
+	return row
+}
+
+func upFilter(prev, data []byte) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		row = append(row, data[i]-get_or(prev, i, 0))
+	}
+	return row
+}
+
+func unUpFilter(prev, data []byte) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		row = append(row, data[i]+get_or(prev, i, 0))
+	}
+	return row
+}
+
+func average(left, up byte) byte {
+	return byte((int(left) + int(up)) / 2)
+}
+
+func averageFilter(prev, data []byte, bpp int) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		row = append(row, data[i]-average(get_or(data, i-bpp, 0), get_or(prev, i, 0)))
+	}
+	return row
+}
+
+func unAverageFilter(prev, data []byte, bpp int) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		row = append(row, data[i]+average(get_or(row, i-bpp, 0), get_or(prev, i, 0)))
+	}
+	return row
+}
+
+func get_or(data []byte, idx int, def byte) byte {
+	if idx < 0 || idx >= len(data) {
+		return def
+	}
+	return data[idx]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// paethPredictor picks whichever of left, up and upper left is closest to
+// left + up - upper left.
+func paethPredictor(left, up, upLeft byte) byte {
+	p := int(left) + int(up) - int(upLeft)
+	pa := abs(p - int(left))
+	pb := abs(p - int(up))
+	pc := abs(p - int(upLeft))
+
+	if pa <= pb && pa <= pc {
+		return left
+	}
+	if pb <= pc {
+		return up
+	}
+	return upLeft
+}
+
+func paethFilter(prev, data []byte, bpp int) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		L := get_or(data, i-bpp, 0)
+		U := get_or(prev, i, 0)
+		UL := get_or(prev, i-bpp, 0)
+		row = append(row, data[i]-paethPredictor(L, U, UL))
+	}
+	return row
+}
+
+func unPaethFilter(prev, data []byte, bpp int) []byte {
+	row := []byte{}
+	for i := 0; i < len(data); i++ {
+		L := get_or(row, i-bpp, 0)
+		U := get_or(prev, i, 0)
+		UL := get_or(prev, i-bpp, 0)
+		row = append(row, data[i]+paethPredictor(L, U, UL))
+	}
+	return row
+}
+
+func filterRow(f Filter, prev, row []byte, bpp int) []byte {
+	switch f {
+	case SUB:
+		return subFilter(row, bpp)
+	case UP:
+		return upFilter(prev, row)
+	case AVERAGE:
+		return averageFilter(prev, row, bpp)
+	case PAETH:
+		return paethFilter(prev, row, bpp)
+	default:
+		return append([]byte{}, row...)
+	}
+}
+
+// FilterStrategy decides how the encoder filters scanlines: always with the
+// same filter, adaptively per row or by trying all of them.
+type FilterStrategy int
+
+const (
+	FILTER_NONE FilterStrategy = iota
+	FILTER_SUB
+	FILTER_UP
+	FILTER_AVERAGE
+	FILTER_PAETH
+	ADAPTIVE
+	BRUTE_FORCE
+)
+
+var FILTER_STRATEGIES = map[string]FilterStrategy{
+	"none":     FILTER_NONE,
+	"sub":      FILTER_SUB,
+	"up":       FILTER_UP,
+	"average":  FILTER_AVERAGE,
+	"paeth":    FILTER_PAETH,
+	"adaptive": ADAPTIVE,
+	"brute":    BRUTE_FORCE,
+}
+
+// apply filters a single row. Brute force works on whole images and is
+// treated as adaptive here.
+func (s FilterStrategy) apply(prev, row []byte, bpp int) (Filter, []byte) {
+	if s < ADAPTIVE {
+		return Filter(s), filterRow(Filter(s), prev, row, bpp)
+	}
+	return adaptiveFilter(prev, row, bpp)
+}
+
+// adaptiveFilter is the heuristic recommended by the png specification: use
+// the filter with the minimum sum of absolute differences, treating the
+// filtered bytes as signed.
+func adaptiveFilter(prev, row []byte, bpp int) (Filter, []byte) {
+	var (
+		best     Filter
+		bestRow  []byte
+		bestCost = -1
+	)
+
+	for f := NONE; f <= PAETH; f++ {
+		filtered := filterRow(f, prev, row, bpp)
+		cost := 0
+		for _, b := range filtered {
+			cost += abs(int(int8(b)))
+		}
+		if bestCost < 0 || cost < bestCost {
+			best, bestRow, bestCost = f, filtered, cost
+		}
+	}
+
+	return best, bestRow
+}
+
+// unfilter reverses the filter of every scanline. rowSize is the number of
+// bytes of a scanline without its leading filter type byte. The result has
+// the filter type bytes removed.
+func unfilter(data []byte, rowSize, height, bpp int) ([]byte, error) {
+	if len(data) < (rowSize+1)*height {
+		return nil, fmt.Errorf("error: expected %d bytes of image data, got %d",
+			(rowSize+1)*height, len(data))
+	}
+
+	unfilteredData := make([]byte, 0, rowSize*height)
+	var prev []byte
+
+	for i := 0; i < height; i++ {
+		start := i * (rowSize + 1)
+		end := start + rowSize + 1
+
+		row := data[start+1 : end]
+		filterByte := Filter(data[start])
+		switch filterByte {
+		case NONE:
+			row = append([]byte{}, row...)
+		case SUB:
+			row = unSubFilter(row, bpp)
+		case UP:
+			row = unUpFilter(prev, row)
+		case AVERAGE:
+			row = unAverageFilter(prev, row, bpp)
+		case PAETH:
+			row = unPaethFilter(prev, row, bpp)
+		default:
+			return nil, fmt.Errorf("error: unknown filter type %d in row %d", filterByte, i)
+		}
+
+		unfilteredData = append(unfilteredData, row...)
+		prev = row
+	}
+
+	return unfilteredData, nil
+}
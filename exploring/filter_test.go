@@ -1,4 +1,4 @@
-package main
+package exploring
 
 import (
 	"bytes"
@@ -9,7 +9,7 @@ import (
 func TestSubFilter(t *testing.T) {
 	incoming := []byte{127, 128, 139, 134, 139, 133, 136, 129}
 	expected := []byte{127, 1, 11, 251, 5, 250, 3, 249}
-	got := subFilter(incoming)
+	got := subFilter(incoming, 1)
 
 	if bytes.Compare(expected, got) != 0 {
 		t.Fatalf("expected %v, but got %v", expected, got)
@@ -19,10 +19,10 @@ func TestSubFilter(t *testing.T) {
 func TestUnSubFilter(t *testing.T) {
 	original := []byte{127, 128, 139, 134, 139, 133, 136, 129}
 	// expected := []byte{127, 1, 11, 251, 5, 250, 3, 249}
-	filtered := subFilter(original)
+	filtered := subFilter(original, 1)
 	fmt.Println("filtered", filtered)
 
-	got := unSubFilter(filtered)
+	got := unSubFilter(filtered, 1)
 
 	if bytes.Compare(original, got) != 0 {
 		t.Fatalf("expected %v, but got %v", original, got)
@@ -54,13 +54,97 @@ func TestUnUpFilter(t *testing.T) {
 }
 
 func TestPaethFilter(t *testing.T) {
-	t.Skip("skipping peath filter atm")
 	prev := []byte{129, 131, 134, 134, 136, 128, 134, 127}
 	incoming := []byte{133, 136, 136, 134, 133, 136, 128, 127}
 	expected := []byte{4, 3, 0, 254, 253, 8, 248, 0}
-	got := paethFilter(prev, incoming)
+	got := paethFilter(prev, incoming, 1)
 
 	if bytes.Compare(expected, got) != 0 {
 		t.Fatalf("expected %v, but got %v", expected, got)
 	}
 }
+
+func TestUnPaethFilter(t *testing.T) {
+	prev := []byte{129, 131, 134, 134, 136, 128, 134, 127}
+	original := []byte{133, 136, 136, 134, 133, 136, 128, 127}
+
+	for bpp := 1; bpp <= 4; bpp++ {
+		filtered := paethFilter(prev, original, bpp)
+		got := unPaethFilter(prev, filtered, bpp)
+
+		if bytes.Compare(original, got) != 0 {
+			t.Fatalf("bpp %d: expected %v, but got %v", bpp, original, got)
+		}
+	}
+}
+
+func TestAverageFilter(t *testing.T) {
+	prev := []byte{10, 20, 30, 40}
+	incoming := []byte{12, 25, 40, 41}
+	// left for the first pixel is 0: 12-5, 25-10, 40-(12+30)/2, 41-(25+40)/2
+	expected := []byte{7, 15, 19, 9}
+	got := averageFilter(prev, incoming, 2)
+
+	if bytes.Compare(expected, got) != 0 {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+
+	if back := unAverageFilter(prev, got, 2); bytes.Compare(incoming, back) != 0 {
+		t.Fatalf("expected %v, but got %v", incoming, back)
+	}
+}
+
+func TestUnfilter(t *testing.T) {
+	rows := [][]byte{
+		{1, 2, 3, 4, 5, 6},
+		{7, 8, 9, 10, 11, 12},
+		{200, 100, 50, 25, 12, 6},
+		{0, 255, 0, 255, 0, 255},
+		{3, 3, 3, 3, 3, 3},
+	}
+	bpp := 3
+
+	var data, expected, prev []byte
+	for i, row := range rows {
+		var filtered []byte
+		switch Filter(i) {
+		case NONE:
+			filtered = row
+		case SUB:
+			filtered = subFilter(row, bpp)
+		case UP:
+			filtered = upFilter(prev, row)
+		case AVERAGE:
+			filtered = averageFilter(prev, row, bpp)
+		case PAETH:
+			filtered = paethFilter(prev, row, bpp)
+		}
+		data = append(data, byte(i))
+		data = append(data, filtered...)
+		expected = append(expected, row...)
+		prev = row
+	}
+
+	got, err := unfilter(data, 6, len(rows), bpp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(expected, got) != 0 {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+}
+
+func TestAdaptiveFilter(t *testing.T) {
+	prev := []byte{10, 20, 30, 40, 50, 60}
+	// identical to the previous row, so up leaves only zeros
+	f, filtered := adaptiveFilter(prev, prev, 3)
+	if f != UP || bytes.Count(filtered, []byte{0}) != len(prev) {
+		t.Fatalf("expected up filter with zeros, but got %s %v", f, filtered)
+	}
+
+	// a gradient without a previous row is cheapest with sub
+	row := []byte{100, 101, 102, 103, 104, 105, 106, 107}
+	if f, _ := adaptiveFilter(nil, row, 1); f != SUB {
+		t.Fatalf("expected sub filter, but got %s", f)
+	}
+}
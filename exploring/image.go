@@ -0,0 +1,120 @@
+package exploring
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Image implements image.Image, so decoded images can be used wherever the
+// standard library expects one.
+
+func (i Image) ColorModel() color.Model {
+	return color.NRGBA64Model
+}
+
+func (i Image) Bounds() image.Rectangle {
+	return image.Rect(0, 0, int(i.Width), int(i.Height))
+}
+
+func (i Image) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(i.Bounds())) {
+		return color.NRGBA64{}
+	}
+	p := i.Pixels[y][x]
+	return color.NRGBA64{R: p.Red, G: p.Green, B: p.Blue, A: p.Alpha}
+}
+
+// colorModel is the model of the standard library image Decode returns.
+func (ihdr IHDR) colorModel(transparent bool) color.Model {
+	switch {
+	case ihdr.ColorType == 0 && !transparent && ihdr.BitDepth == 16:
+		return color.Gray16Model
+	case ihdr.ColorType == 0 && !transparent:
+		return color.GrayModel
+	case ihdr.BitDepth == 16:
+		return color.NRGBA64Model
+	default:
+		return color.NRGBAModel
+	}
+}
+
+// Decode reads a png with the exploring decoder. Grayscale images without
+// transparency become *image.Gray or *image.Gray16, all others *image.NRGBA
+// or, with 16 bits per sample, *image.NRGBA64.
+func Decode(r io.Reader) (image.Image, error) {
+	png, err := ReadPNG(r)
+	if err != nil {
+		return nil, err
+	}
+
+	src := png.Image
+	rect := src.Bounds()
+
+	switch png.IHDR.colorModel(png.transparent != nil) {
+	case color.Gray16Model:
+		img := image.NewGray16(rect)
+		for y, row := range src.Pixels {
+			for x, p := range row {
+				img.SetGray16(x, y, color.Gray16{Y: p.Red})
+			}
+		}
+		return img, nil
+	case color.GrayModel:
+		img := image.NewGray(rect)
+		for y, row := range src.Pixels {
+			for x, p := range row {
+				img.SetGray(x, y, color.Gray{Y: uint8(p.Red >> 8)})
+			}
+		}
+		return img, nil
+	case color.NRGBA64Model:
+		img := image.NewNRGBA64(rect)
+		for y, row := range src.Pixels {
+			for x, p := range row {
+				img.SetNRGBA64(x, y, color.NRGBA64{R: p.Red, G: p.Green, B: p.Blue, A: p.Alpha})
+			}
+		}
+		return img, nil
+	default:
+		img := image.NewNRGBA(rect)
+		for y, row := range src.Pixels {
+			for x, p := range row {
+				img.SetNRGBA(x, y, color.NRGBA{
+					R: uint8(p.Red >> 8), G: uint8(p.Green >> 8), B: uint8(p.Blue >> 8), A: uint8(p.Alpha >> 8),
+				})
+			}
+		}
+		return img, nil
+	}
+}
+
+// DecodeConfig reads only the signature and the IHDR chunk. Whether a
+// tRNS chunk follows is not known at that point, so grayscale images report
+// the gray color models.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	if err := read_signature(r); err != nil {
+		return image.Config{}, err
+	}
+
+	chunk, err := read_chunk(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if chunk.ChunkType.String() != "IHDR" || chunk.Size != 13 {
+		return image.Config{}, fmt.Errorf("error: expected a 13 byte IHDR chunk first, got %s with %d bytes",
+			chunk.ChunkType, chunk.Size)
+	}
+
+	ihdr := NewIHDR(chunk)
+	if err := ihdr.validate(); err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: ihdr.colorModel(false),
+		Width:      ihdr.Width,
+		Height:     ihdr.Height,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package exploring
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDecodeImageTypes(t *testing.T) {
+	rect := image.Rect(0, 0, 6, 4)
+	paletted := image.NewPaletted(rect, color.Palette{color.NRGBA{1, 2, 3, 4}, color.NRGBA{5, 6, 7, 255}})
+	paletted.SetColorIndex(1, 1, 1)
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		expected string
+	}{
+		{"gray", image.NewGray(rect), "*image.Gray"},
+		{"gray16", image.NewGray16(rect), "*image.Gray16"},
+		{"rgb", image.NewRGBA(rect), "*image.NRGBA"},
+		{"nrgba", image.NewNRGBA(rect), "*image.NRGBA"},
+		{"nrgba64", image.NewNRGBA64(rect), "*image.NRGBA64"},
+		{"paletted", paletted, "*image.NRGBA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if set, ok := tt.img.(interface{ Set(x, y int, c color.Color) }); ok && tt.name != "paletted" {
+				for y := range 4 {
+					for x := range 6 {
+						set.Set(x, y, color.NRGBA64{uint16(x * 9000), uint16(y * 15000), 0x1234, 0xffff - uint16(x*100)})
+					}
+				}
+			}
+			file := encodeStd(t, tt.img)
+
+			img, err := Decode(bytes.NewReader(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%T", img); got != tt.expected {
+				t.Fatalf("expected %s, but got %s", tt.expected, got)
+			}
+
+			png, err := ReadPNG(bytes.NewReader(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			comparePixels(t, file, png.Image)
+
+			for y := range 4 {
+				for x := range 6 {
+					want := nrgba64(png.Image.At(x, y))
+					if got := nrgba64(img.At(x, y)); got != want && want.A != 0 {
+						t.Fatalf("(%d, %d): expected %v, but got %v", x, y, want, got)
+					}
+				}
+			}
+
+			config, err := DecodeConfig(bytes.NewReader(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != 6 || config.Height != 4 {
+				t.Errorf("expected 6x4, but got %dx%d", config.Width, config.Height)
+			}
+		})
+	}
+}
+
+// image.Decode keeps using image/png, the package registers nothing
+func TestNotRegistered(t *testing.T) {
+	opaque := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := range opaque.Pix {
+		opaque.Pix[i] = 0xff
+	}
+	// image/png decodes truecolor to *image.RGBA, the exploring decoder to
+	// *image.NRGBA
+	file := encodeStd(t, opaque)
+	img, format, err := image.Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%T", img); format != "png" || got != "*image.RGBA" {
+		t.Errorf("expected image/png to decode an *image.RGBA, but got %s from %s", got, format)
+	}
+}
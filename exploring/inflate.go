@@ -0,0 +1,506 @@
+package exploring
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A DEFLATE stream (RFC 1951) is a sequence of blocks. Each starts with a
+// final flag and a block type: stored blocks copy bytes, Huffman blocks hold
+// literals and back references to the last 32 KiB coded either with fixed
+// codes or with codes described at the start of the block. zlib (RFC 1950)
+// wraps the stream in a two byte header and an Adler-32 checksum.
+// https://www.rfc-editor.org/rfc/rfc1951
+
+// INFLATERS are the zlib decoders decompress_zlib can use.
+var INFLATERS = map[string]func(data []byte) ([]byte, error){
+	"zlib":   inflateStd,
+	"native": func(data []byte) ([]byte, error) { return Inflate(data, InflateTrace) },
+}
+
+// Inflater is the zlib decoder used for image data, compress/zlib by default.
+var Inflater = INFLATERS["zlib"]
+
+// InflateTrace receives the block structure of every stream the native
+// inflater decodes if it is not nil.
+var InflateTrace io.Writer
+
+var errUnexpectedEnd = errors.New("error: unexpected end of deflate data")
+
+type blockType uint32
+
+const (
+	STORED blockType = iota
+	FIXED
+	DYNAMIC
+)
+
+func (b blockType) String() string {
+	switch b {
+	case STORED:
+		return "stored"
+	case FIXED:
+		return "fixed huffman"
+	case DYNAMIC:
+		return "dynamic huffman"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint32(b))
+	}
+}
+
+// bitReader reads the bits of a byte slice starting with the least
+// significant bit of each byte.
+type bitReader struct {
+	data []byte
+	pos  int
+	bit  uint
+
+	// ends records, if track is set, the bit offset reached once each
+	// output byte was produced.
+	track bool
+	ends  []int
+}
+
+func (br *bitReader) bits(n uint) (uint32, error) {
+	var v uint32
+	for i := range n {
+		if br.pos >= len(br.data) {
+			return 0, errUnexpectedEnd
+		}
+		v |= uint32(br.data[br.pos]>>br.bit&1) << i
+		br.bit++
+		if br.bit == 8 {
+			br.pos++
+			br.bit = 0
+		}
+	}
+	return v, nil
+}
+
+// align skips to the next byte boundary.
+func (br *bitReader) align() {
+	if br.bit != 0 {
+		br.pos++
+		br.bit = 0
+	}
+}
+
+// offset is the number of bits read so far.
+func (br *bitReader) offset() int {
+	return br.pos*8 + int(br.bit)
+}
+
+// produced records the current offset for the output bytes up to n.
+func (br *bitReader) produced(n int) {
+	if !br.track {
+		return
+	}
+	for len(br.ends) < n {
+		br.ends = append(br.ends, br.offset())
+	}
+}
+
+// huffman is a canonical Huffman code given by the number of codes of each
+// length and the symbols sorted by code.
+type huffman struct {
+	counts  [16]int
+	symbols []int
+}
+
+func newHuffman(lengths []uint8) (huffman, error) {
+	var h huffman
+	for _, l := range lengths {
+		h.counts[l]++
+	}
+	h.counts[0] = 0
+
+	// more codes of a length than there is room for make the code invalid,
+	// fewer are allowed for the distance codes of short blocks
+	left := 1
+	for l := 1; l < len(h.counts); l++ {
+		left = left<<1 - h.counts[l]
+		if left < 0 {
+			return huffman{}, fmt.Errorf("error: oversubscribed huffman code")
+		}
+	}
+
+	var offsets [16]int
+	for l := 1; l < len(offsets)-1; l++ {
+		offsets[l+1] = offsets[l] + h.counts[l]
+	}
+	h.symbols = make([]int, offsets[15]+h.counts[15])
+	for symbol, l := range lengths {
+		if l != 0 {
+			h.symbols[offsets[l]] = symbol
+			offsets[l]++
+		}
+	}
+
+	return h, nil
+}
+
+// decode reads a code bit by bit. Huffman codes are stored starting with
+// their most significant bit.
+func (h huffman) decode(br *bitReader) (int, error) {
+	code, first, index := 0, 0, 0
+	for l := 1; l < len(h.counts); l++ {
+		bit, err := br.bits(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= int(bit)
+		count := h.counts[l]
+		if code-first < count {
+			return h.symbols[index+code-first], nil
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	return 0, fmt.Errorf("error: invalid huffman code")
+}
+
+// Base values and extra bits of the length symbols 257 to 285 and the
+// distance symbols 0 to 29.
+var (
+	lengthBase  = []int{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+	lengthExtra = []uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	distBase    = []int{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+	distExtra   = []uint{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+)
+
+// codeLengthOrder is the order the lengths of the code length code are
+// stored in.
+var codeLengthOrder = []int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+// fixedCodes returns the literal/length and distance codes of block type 1.
+func fixedCodes() (huffman, huffman) {
+	lengths := make([]uint8, 288+30)
+	for i := range 288 {
+		switch {
+		case i < 144:
+			lengths[i] = 8
+		case i < 256:
+			lengths[i] = 9
+		case i < 280:
+			lengths[i] = 7
+		default:
+			lengths[i] = 8
+		}
+	}
+	for i := 288; i < len(lengths); i++ {
+		lengths[i] = 5
+	}
+
+	literals, _ := newHuffman(lengths[:288])
+	distances, _ := newHuffman(lengths[288:])
+	return literals, distances
+}
+
+// Inflate decodes a zlib stream and checks its Adler-32 checksum. If trace
+// is not nil, the header and every block are described there.
+func Inflate(data []byte, trace io.Writer) ([]byte, error) {
+	out, _, err := inflateZlib(data, trace, false)
+	return out, err
+}
+
+// InflatePositions decodes a zlib stream and returns, for every byte of the
+// output, the bit offset in data the decoder had reached once it was
+// produced. The bits of a back reference are attributed to its last byte.
+func InflatePositions(data []byte) ([]byte, []int, error) {
+	return inflateZlib(data, nil, true)
+}
+
+func inflateZlib(data []byte, trace io.Writer, track bool) ([]byte, []int, error) {
+	if len(data) < 6 {
+		return nil, nil, fmt.Errorf("error: zlib stream of %d bytes is too short", len(data))
+	}
+
+	cmf, flg := data[0], data[1]
+	if cmf&0x0f != 8 || cmf>>4 > 7 {
+		return nil, nil, fmt.Errorf("error: zlib compression method %d with window %d is not deflate",
+			cmf&0x0f, cmf>>4)
+	}
+	if (uint16(cmf)<<8|uint16(flg))%31 != 0 {
+		return nil, nil, fmt.Errorf("error: zlib header check failed")
+	}
+	if flg&0x20 != 0 {
+		return nil, nil, fmt.Errorf("error: zlib preset dictionaries are not supported")
+	}
+	if trace != nil {
+		fmt.Fprintf(trace, "zlib: window %d bytes, level %d\n", 1<<(cmf>>4+8), flg>>6)
+	}
+
+	br := &bitReader{data: data[2:], track: track}
+	out, err := inflate(br, trace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	br.align()
+	if len(br.data)-br.pos < 4 {
+		return nil, nil, fmt.Errorf("error: zlib stream without Adler-32 checksum")
+	}
+	expected := binary.BigEndian.Uint32(br.data[br.pos:])
+	if checksum := adler32(out); checksum != expected {
+		return nil, nil, fmt.Errorf("error: Adler-32 checksum %08x does not match %08x", checksum, expected)
+	}
+	if trace != nil {
+		fmt.Fprintf(trace, "adler-32: %08x\n", expected)
+	}
+
+	ends := br.ends
+	for i := range ends {
+		// count the two bytes of the zlib header
+		ends[i] += 16
+	}
+
+	return out, ends, nil
+}
+
+// inflate decodes raw DEFLATE blocks until the final one.
+func inflate(br *bitReader, trace io.Writer) ([]byte, error) {
+	var out []byte
+
+	for i := 0; ; i++ {
+		start, size := br.offset(), len(out)
+
+		final, err := br.bits(1)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := br.bits(2)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			description string
+			stats       blockStats
+		)
+		switch blockType(typ) {
+		case STORED:
+			out, err = inflateStored(br, out)
+		case FIXED:
+			literals, distances := fixedCodes()
+			out, stats, err = inflateCodes(br, out, literals, distances)
+		case DYNAMIC:
+			var literals, distances huffman
+			literals, distances, description, err = readCodes(br)
+			if err == nil {
+				out, stats, err = inflateCodes(br, out, literals, distances)
+			}
+		default:
+			err = fmt.Errorf("error: invalid deflate block type 3")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error in deflate block %d: %v", i, err)
+		}
+
+		if trace != nil {
+			fmt.Fprintf(trace, "block %d: %s, bits %d-%d, %d bytes", i, blockType(typ), start, br.offset(), len(out)-size)
+			if blockType(typ) != STORED {
+				fmt.Fprintf(trace, ", %d literals, %d matches", stats.literals, stats.matches)
+			}
+			if description != "" {
+				fmt.Fprintf(trace, ", %s", description)
+			}
+			if final == 1 {
+				fmt.Fprint(trace, ", final")
+			}
+			fmt.Fprintln(trace)
+		}
+
+		if final == 1 {
+			return out, nil
+		}
+	}
+}
+
+func inflateStored(br *bitReader, out []byte) ([]byte, error) {
+	br.align()
+	if len(br.data)-br.pos < 4 {
+		return nil, errUnexpectedEnd
+	}
+
+	length := binary.LittleEndian.Uint16(br.data[br.pos:])
+	nlength := binary.LittleEndian.Uint16(br.data[br.pos+2:])
+	if length != ^nlength {
+		return nil, fmt.Errorf("error: stored block length %d does not match its complement", length)
+	}
+	br.pos += 4
+
+	if len(br.data)-br.pos < int(length) {
+		return nil, errUnexpectedEnd
+	}
+	for _, b := range br.data[br.pos : br.pos+int(length)] {
+		out = append(out, b)
+		br.pos++
+		br.produced(len(out))
+	}
+
+	return out, nil
+}
+
+// readCodes reads the code lengths of a dynamic block, which are themselves
+// Huffman coded.
+func readCodes(br *bitReader) (huffman, huffman, string, error) {
+	var counts [3]uint32
+	for i, n := range []uint{5, 5, 4} {
+		v, err := br.bits(n)
+		if err != nil {
+			return huffman{}, huffman{}, "", err
+		}
+		counts[i] = v
+	}
+	nlen, ndist, ncode := int(counts[0])+257, int(counts[1])+1, int(counts[2])+4
+	if nlen > 286 || ndist > 30 {
+		return huffman{}, huffman{}, "", fmt.Errorf("error: %d literal/length and %d distance codes", nlen, ndist)
+	}
+
+	codeLengths := make([]uint8, 19)
+	for _, i := range codeLengthOrder[:ncode] {
+		v, err := br.bits(3)
+		if err != nil {
+			return huffman{}, huffman{}, "", err
+		}
+		codeLengths[i] = uint8(v)
+	}
+	lengthCode, err := newHuffman(codeLengths)
+	if err != nil {
+		return huffman{}, huffman{}, "", err
+	}
+
+	lengths := make([]uint8, 0, nlen+ndist)
+	for len(lengths) < nlen+ndist {
+		symbol, err := lengthCode.decode(br)
+		if err != nil {
+			return huffman{}, huffman{}, "", err
+		}
+
+		if symbol < 16 {
+			lengths = append(lengths, uint8(symbol))
+			continue
+		}
+
+		var (
+			value  uint8
+			repeat uint32
+		)
+		switch symbol {
+		case 16:
+			if len(lengths) == 0 {
+				return huffman{}, huffman{}, "", fmt.Errorf("error: repeated code length without a previous one")
+			}
+			value = lengths[len(lengths)-1]
+			repeat, err = br.bits(2)
+			repeat += 3
+		case 17:
+			repeat, err = br.bits(3)
+			repeat += 3
+		case 18:
+			repeat, err = br.bits(7)
+			repeat += 11
+		}
+		if err != nil {
+			return huffman{}, huffman{}, "", err
+		}
+		if len(lengths)+int(repeat) > nlen+ndist {
+			return huffman{}, huffman{}, "", fmt.Errorf("error: code lengths exceed %d codes", nlen+ndist)
+		}
+		for range repeat {
+			lengths = append(lengths, value)
+		}
+	}
+
+	if lengths[256] == 0 {
+		return huffman{}, huffman{}, "", fmt.Errorf("error: dynamic block without end of block code")
+	}
+
+	literals, err := newHuffman(lengths[:nlen])
+	if err != nil {
+		return huffman{}, huffman{}, "", err
+	}
+	distances, err := newHuffman(lengths[nlen:])
+	if err != nil {
+		return huffman{}, huffman{}, "", err
+	}
+
+	description := fmt.Sprintf("%d literal/length codes, %d distance codes, %d code length codes", nlen, ndist, ncode)
+	return literals, distances, description, nil
+}
+
+type blockStats struct {
+	literals int
+	matches  int
+}
+
+// inflateCodes decodes the literals and back references of a Huffman block
+// up to the end of block symbol 256.
+func inflateCodes(br *bitReader, out []byte, literals, distances huffman) ([]byte, blockStats, error) {
+	var stats blockStats
+
+	for {
+		symbol, err := literals.decode(br)
+		if err != nil {
+			return nil, stats, err
+		}
+
+		switch {
+		case symbol < 256:
+			out = append(out, byte(symbol))
+			br.produced(len(out))
+			stats.literals++
+			continue
+		case symbol == 256:
+			return out, stats, nil
+		case symbol > 285:
+			return nil, stats, fmt.Errorf("error: invalid length symbol %d", symbol)
+		}
+
+		symbol -= 257
+		extra, err := br.bits(lengthExtra[symbol])
+		if err != nil {
+			return nil, stats, err
+		}
+		length := lengthBase[symbol] + int(extra)
+
+		symbol, err = distances.decode(br)
+		if err != nil {
+			return nil, stats, err
+		}
+		if symbol >= len(distBase) {
+			return nil, stats, fmt.Errorf("error: invalid distance symbol %d", symbol)
+		}
+		extra, err = br.bits(distExtra[symbol])
+		if err != nil {
+			return nil, stats, err
+		}
+		distance := distBase[symbol] + int(extra)
+
+		if distance > len(out) {
+			return nil, stats, fmt.Errorf("error: distance %d reaches before the start of the data", distance)
+		}
+		// the copy can overlap the bytes it produces, so go byte by byte
+		start := len(out) - distance
+		for i := range length {
+			out = append(out, out[start+i])
+		}
+		br.produced(len(out))
+		stats.matches++
+	}
+}
+
+// adler32 is the checksum of zlib streams: two sums modulo 65521, one of the
+// bytes and one of the running first sum.
+func adler32(data []byte) uint32 {
+	const mod = 65521
+	a, b := uint32(1), uint32(0)
+	for _, d := range data {
+		a = (a + uint32(d)) % mod
+		b = (b + a) % mod
+	}
+	return b<<16 | a
+}
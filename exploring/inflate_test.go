@@ -0,0 +1,126 @@
+package exploring
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"testing"
+)
+
+func deflate(t *testing.T, data []byte, level int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInflateBlockTypes(t *testing.T) {
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 50))
+
+	tests := []struct {
+		name  string
+		data  []byte
+		level int
+		block string
+	}{
+		{"stored", text, zlib.NoCompression, "stored"},
+		{"fixed", []byte("aaaaaaaaaaaaaaaaaaaaab"), zlib.BestCompression, "fixed huffman"},
+		{"dynamic", text, zlib.BestCompression, "dynamic huffman"},
+		{"empty", nil, zlib.DefaultCompression, "final"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trace strings.Builder
+			got, err := Inflate(deflate(t, tt.data, tt.level), &trace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Fatalf("expected %q, but got %q", tt.data, got)
+			}
+			if !strings.Contains(trace.String(), tt.block) {
+				t.Errorf("expected a %s block in the trace:\n%s", tt.block, trace.String())
+			}
+		})
+	}
+}
+
+func TestInflateChecksum(t *testing.T) {
+	data := deflate(t, []byte("hello, hello, hello"), zlib.BestCompression)
+	data[len(data)-1]++
+
+	if _, err := Inflate(data, nil); err == nil || !strings.Contains(err.Error(), "Adler-32") {
+		t.Fatalf("expected an Adler-32 error, but got %v", err)
+	}
+}
+
+func TestInflateCorpus(t *testing.T) {
+	for _, file := range Corpus() {
+		for _, level := range []int{zlib.NoCompression, zlib.BestSpeed, zlib.BestCompression} {
+			file.Encoder.Level = level
+			testInflatePNG(t, file)
+		}
+	}
+}
+
+func testInflatePNG(t *testing.T, file CorpusFile) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := file.Encoder.Encode(&buf, file.Image); err != nil {
+		t.Fatal(err)
+	}
+	png, err := ReadPNG(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var idat []byte
+	for _, c := range png.Chunks {
+		if c.ChunkType.String() == "IDAT" {
+			idat = append(idat, c.Data...)
+		}
+	}
+
+	expected, err := inflateStd(idat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Inflate(idat, nil)
+	if err != nil {
+		t.Fatalf("%s at level %d: %v", file.Name, file.Encoder.Level, err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("%s at level %d: inflated data differs from compress/zlib", file.Name, file.Encoder.Level)
+	}
+}
+
+func TestInflatePositions(t *testing.T) {
+	for _, level := range []int{zlib.NoCompression, zlib.BestCompression} {
+		data := deflate(t, bytes.Repeat([]byte("abcdefgh"), 100), level)
+
+		out, ends, err := InflatePositions(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ends) != len(out) {
+			t.Fatalf("level %d: expected %d positions, but got %d", level, len(out), len(ends))
+		}
+		for i := 1; i < len(ends); i++ {
+			if ends[i] < ends[i-1] {
+				t.Fatalf("level %d: position %d goes back from %d to %d", level, i, ends[i-1], ends[i])
+			}
+		}
+		// everything but the 4 byte checksum
+		if last := ends[len(ends)-1]; last <= 16 || last > 8*(len(data)-4) {
+			t.Errorf("level %d: last byte ends at bit %d of %d", level, last, 8*len(data))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package exploring
+
+// pass describes which pixels of the image an interlace pass contains: every
+// dx-th pixel of every dy-th row, starting at x, y.
+type pass struct {
+	x, y, dx, dy int
+}
+
+// ADAM7 are the seven passes of interlace method 1.
+var ADAM7 = []pass{
+	{0, 0, 8, 8},
+	{4, 0, 8, 8},
+	{0, 4, 4, 8},
+	{2, 0, 4, 4},
+	{0, 2, 2, 4},
+	{1, 0, 2, 2},
+	{0, 1, 1, 2},
+}
+
+// noInterlace is a single pass over the whole image.
+var noInterlace = []pass{{0, 0, 1, 1}}
+
+func (ihdr IHDR) passes() []pass {
+	if ihdr.InterlaceMethod == 1 {
+		return ADAM7
+	}
+	return noInterlace
+}
+
+// size is the width and height of the reduced image of the pass. A pass
+// can be empty for small images.
+func (p pass) size(width, height int) (int, int) {
+	w := (width - p.x + p.dx - 1) / p.dx
+	h := (height - p.y + p.dy - 1) / p.dy
+	return max(w, 0), max(h, 0)
+}
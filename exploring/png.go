@@ -1,105 +1,375 @@
-package main
+package exploring
 
 import (
+	"bytes"
+	"compress/zlib"
 	"encoding/binary"
-	"hash/crc32"
+	"fmt"
+	"io"
+
+	"github.com/aaronbittel/color-picker/pngchunk"
 )
 
 type RGB struct {
-	red   byte
-	green byte
-	blue  byte
+	Red   byte
+	Green byte
+	Blue  byte
+}
+
+// Pixel is a non-premultiplied color with 16 bits per channel. Samples of
+// lower bit depths are scaled up, so 8 bit samples are multiplied by 257.
+type Pixel struct {
+	Red   uint16
+	Green uint16
+	Blue  uint16
+	Alpha uint16
 }
 
 type Image struct {
-	width  uint32
-	height uint32
-	pixels [][]RGB
+	Width  uint32
+	Height uint32
+	Pixels [][]Pixel
 }
 
 func NewImage(width, height uint32) Image {
-	pixels := make([][]RGB, height)
+	pixels := make([][]Pixel, height)
 	for i := range height {
-		pixels[i] = make([]RGB, width)
+		pixels[i] = make([]Pixel, width)
 	}
 
 	return Image{
-		width:  width,
-		height: height,
-		pixels: pixels,
+		Width:  width,
+		Height: height,
+		Pixels: pixels,
 	}
 }
 
 func (i *Image) Fill(color RGB) {
-	for y := range i.height {
-		for x := range i.width {
-			i.pixels[y][x] = color
+	for y := range i.Height {
+		for x := range i.Width {
+			i.Pixels[y][x] = Pixel{
+				Red:   uint16(color.Red) * 257,
+				Green: uint16(color.Green) * 257,
+				Blue:  uint16(color.Blue) * 257,
+				Alpha: 0xffff,
+			}
 		}
 	}
 }
 
+func (i Image) clone() Image {
+	c := NewImage(i.Width, i.Height)
+	for y := range i.Pixels {
+		copy(c.Pixels[y], i.Pixels[y])
+	}
+	return c
+}
+
 func (i Image) Bytes() []byte {
-	buf := make([]byte, 3*i.height*i.width)
+	buf := make([]byte, 0, 3*i.Height*i.Width)
 
-	for y := range i.height {
-		for x := range i.width {
-			p := i.pixels[y][x]
-			buf = append(buf, p.red, p.green, p.blue)
+	for y := range i.Height {
+		for x := range i.Width {
+			p := i.Pixels[y][x]
+			buf = append(buf, byte(p.Red>>8), byte(p.Green>>8), byte(p.Blue>>8))
 		}
 	}
 
 	return buf
 }
 
-func Decode(data []byte, width, height uint32) []byte {
-	var png []byte
+// opaque reports whether every pixel has full alpha.
+func (i Image) opaque() bool {
+	for _, row := range i.Pixels {
+		for _, p := range row {
+			if p.Alpha != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// gray converts a pixel with the same weights as color.Gray16Model.
+func gray(p Pixel) uint16 {
+	if p.Red == p.Green && p.Green == p.Blue {
+		return p.Red
+	}
+	y := (19595*uint32(p.Red) + 38470*uint32(p.Green) + 7471*uint32(p.Blue) + 1<<15) >> 16
+	return uint16(y)
+}
+
+// toDepth reduces a 16 bit sample to the given bit depth.
+func toDepth(v uint16, depth byte) uint16 {
+	maxValue := uint32(1)<<depth - 1
+	return uint16((uint32(v)*maxValue + 0xffff/2) / 0xffff)
+}
+
+// packSamples writes samples of the given bit depth into a scanline. Samples
+// smaller than a byte are packed starting with the most significant bit.
+func packSamples(samples []uint16, depth byte) []byte {
+	row := make([]byte, (len(samples)*int(depth)+7)/8)
+	for i, s := range samples {
+		switch depth {
+		case 16:
+			binary.BigEndian.PutUint16(row[2*i:], s)
+		case 8:
+			row[i] = byte(s)
+		default:
+			bit := i * int(depth)
+			row[bit/8] |= byte(s) << (8 - int(depth) - bit%8)
+		}
+	}
+	return row
+}
+
+// Encoder holds the settings used to write a png.
+type Encoder struct {
+	// Filter selects the filter of every scanline.
+	Filter FilterStrategy
+	// Level is the zlib compression level, zlib.DefaultCompression or
+	// between zlib.NoCompression and zlib.BestCompression.
+	Level int
+	// Chunks are ancillary chunks written in front of the image data.
+	Chunks []Chunk
+
+	// ColorType and BitDepth select the pixel format. With a BitDepth of 0
+	// the image is written as 8 bit truecolor, with alpha if needed.
+	ColorType ColorType
+	BitDepth  byte
+	// Interlace writes the image with the Adam7 interlace method.
+	Interlace bool
+}
 
+// Encode writes img as an 8 bit truecolor png with adaptive filtering.
+func Encode(w io.Writer, img Image) error {
+	return Encoder{Filter: ADAPTIVE, Level: zlib.DefaultCompression}.Encode(w, img)
+}
+
+// header builds the IHDR the image is written with.
+func (e Encoder) header(img Image) IHDR {
+	ihdr := IHDR{
+		Width:     int(img.Width),
+		Height:    int(img.Height),
+		BitDepth:  e.BitDepth,
+		ColorType: e.ColorType,
+	}
+
+	if e.BitDepth == 0 {
+		ihdr.BitDepth = 8
+		ihdr.ColorType = 2
+		if !img.opaque() {
+			ihdr.ColorType = 6
+		}
+	}
+	if e.Interlace {
+		ihdr.InterlaceMethod = 1
+	}
+
+	return ihdr
+}
+
+// Encode writes img in the format selected by the encoder. Indexed color
+// images get a palette of the distinct colors of img, which must fit into
+// the bit depth.
+func (e Encoder) Encode(w io.Writer, img Image) error {
+	if img.Width == 0 || img.Height == 0 {
+		return fmt.Errorf("error: cannot encode an empty %dx%d image", img.Width, img.Height)
+	}
+
+	ihdr := e.header(img)
+	if err := ihdr.validate(); err != nil {
+		return err
+	}
+
+	var (
+		palette []Pixel
+		index   map[Pixel]uint16
+	)
+	if ihdr.ColorType == 3 {
+		var err error
+		palette, index, err = buildPalette(img, ihdr.BitDepth)
+		if err != nil {
+			return err
+		}
+	}
+
+	passes := make([][][]byte, 0, len(ihdr.passes()))
+	for _, p := range ihdr.passes() {
+		passes = append(passes, ihdr.passScanlines(img, p, index))
+	}
+
+	idat, err := e.compress(passes, ihdr.bytesPerPixel())
+	if err != nil {
+		return err
+	}
+
+	var png []byte
 	png = append(png, PNG_SIGNATURE...)
-	png = append(png, IHDRChunk(width, height)...)
-	png = append(png, IDATChunk()...)
+	png = append(png, ihdr.Bytes()...)
+	for _, c := range e.Chunks {
+		png = append(png, ChunkBytes(c.ChunkType.String(), c.Data)...)
+	}
+	if palette != nil {
+		png = append(png, paletteChunks(palette)...)
+	}
+	png = append(png, IDATChunk(idat)...)
 	png = append(png, IENDChunk()...)
 
-	return png
+	_, err = w.Write(png)
+	return err
 }
 
-func IDATChunk() []byte {
-	var buf []byte
+// buildPalette collects the distinct colors of img in the order they appear.
+func buildPalette(img Image, depth byte) ([]Pixel, map[Pixel]uint16, error) {
+	var palette []Pixel
+	index := make(map[Pixel]uint16)
 
-	// length
-	buf = append(buf, 0)
+	for _, row := range img.Pixels {
+		for _, p := range row {
+			if _, ok := index[p]; ok {
+				continue
+			}
+			if len(palette) == 1<<depth {
+				return nil, nil, fmt.Errorf("error: image has more than %d colors, too many for a %d bit palette",
+					1<<depth, depth)
+			}
+			index[p] = uint16(len(palette))
+			palette = append(palette, p)
+		}
+	}
 
-	// chunk type
-	chunkType := []byte{73, 68, 65, 84}
-	buf = append(buf, chunkType...)
+	return palette, index, nil
+}
 
-	// chunk data
-	var chunkData byte = 0
-	buf = append(buf, chunkData)
+// paletteChunks returns the PLTE chunk and, if any entry is not opaque, the
+// tRNS chunk with the alpha values up to the last translucent entry.
+func paletteChunks(palette []Pixel) []byte {
+	var plte, trns []byte
+	last := -1
+	for i, p := range palette {
+		plte = append(plte, byte(p.Red>>8), byte(p.Green>>8), byte(p.Blue>>8))
+		trns = append(trns, byte(p.Alpha>>8))
+		if p.Alpha>>8 != 0xff {
+			last = i
+		}
+	}
 
-	// crc
-	buf = append(buf, byte(crc32.ChecksumIEEE(append(chunkType, chunkData))))
+	chunks := ChunkBytes("PLTE", plte)
+	if last >= 0 {
+		chunks = append(chunks, ChunkBytes("tRNS", trns[:last+1])...)
+	}
+	return chunks
+}
 
-	return buf
+// passScanlines returns the unfiltered scanlines of one interlace pass.
+func (ihdr IHDR) passScanlines(img Image, p pass, index map[Pixel]uint16) [][]byte {
+	width, height := p.size(ihdr.Width, ihdr.Height)
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	channels := ihdr.ColorType.channels()
+	rows := make([][]byte, 0, height)
+
+	for row := range height {
+		samples := make([]uint16, 0, width*channels)
+		for col := range width {
+			px := img.Pixels[p.y+row*p.dy][p.x+col*p.dx]
+			switch ihdr.ColorType {
+			case 0:
+				samples = append(samples, gray(px))
+			case 2:
+				samples = append(samples, px.Red, px.Green, px.Blue)
+			case 3:
+				samples = append(samples, index[px])
+				continue
+			case 4:
+				samples = append(samples, gray(px), px.Alpha)
+			case 6:
+				samples = append(samples, px.Red, px.Green, px.Blue, px.Alpha)
+			}
+			for i := len(samples) - channels; i < len(samples); i++ {
+				samples[i] = toDepth(samples[i], ihdr.BitDepth)
+			}
+		}
+		rows = append(rows, packSamples(samples, ihdr.BitDepth))
+	}
+
+	return rows
 }
 
-func IENDChunk() []byte {
-	var buf []byte
+// compress filters and compresses the image data. Brute force tries every
+// other strategy and keeps the smallest result.
+func (e Encoder) compress(passes [][][]byte, bpp int) ([]byte, error) {
+	if e.Filter != BRUTE_FORCE {
+		return compress_zlib(filterPasses(passes, bpp, e.Filter), e.Level)
+	}
 
-	// length
-	buf = append(buf, 0)
+	var best []byte
+	for _, strategy := range []FilterStrategy{ADAPTIVE, FILTER_NONE, FILTER_SUB, FILTER_UP, FILTER_AVERAGE, FILTER_PAETH} {
+		idat, err := compress_zlib(filterPasses(passes, bpp, strategy), e.Level)
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || len(idat) < len(best) {
+			best = idat
+		}
+	}
+	return best, nil
+}
 
-	// chunk type
-	chunkType := []byte{73, 69, 78, 68}
-	buf = append(buf, chunkType...)
+// filterPasses prefixes every scanline with its filter type and applies the
+// filter. Each interlace pass starts without a previous row.
+func filterPasses(passes [][][]byte, bpp int, strategy FilterStrategy) []byte {
+	var data []byte
 
-	// chunk data
-	var chunkData byte = 0
-	buf = append(buf, chunkData)
+	for _, rows := range passes {
+		var prev []byte
+		for _, row := range rows {
+			f, filtered := strategy.apply(prev, row, bpp)
+			data = append(data, byte(f))
+			data = append(data, filtered...)
+			prev = row
+		}
+	}
 
-	// crc
-	buf = append(buf, byte(crc32.ChecksumIEEE(append(chunkType, chunkData))))
+	return data
+}
 
-	return buf
+// filterImage filters img as 8 bit truecolor without interlacing.
+func filterImage(img Image, colorType ColorType, strategy FilterStrategy) []byte {
+	ihdr := IHDR{Width: int(img.Width), Height: int(img.Height), BitDepth: 8, ColorType: colorType}
+	rows := ihdr.passScanlines(img, noInterlace[0], nil)
+	return filterPasses([][][]byte{rows}, ihdr.bytesPerPixel(), strategy)
+}
+
+func compress_zlib(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, fmt.Errorf("error: creating zlib writer: %v", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("error: writing zlib data: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("error: writing zlib data: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ChunkBytes frames data as a png chunk: length, type, data and crc.
+func ChunkBytes(chunkType string, data []byte) []byte {
+	return pngchunk.Chunk{Type: chunkType, Data: data}.Bytes()
+}
+
+func IDATChunk(data []byte) []byte {
+	return ChunkBytes("IDAT", data)
+}
+
+func IENDChunk() []byte {
+	return ChunkBytes("IEND", nil)
 }
 
 func uint32ToBytesBE(n uint32) []byte {
@@ -108,22 +378,23 @@ func uint32ToBytesBE(n uint32) []byte {
 	return buf
 }
 
-func IHDRChunk(width, height uint32) []byte {
+func IHDRChunk(width, height uint32, colorType ColorType) []byte {
+	ihdr := IHDR{Width: int(width), Height: int(height), BitDepth: 8, ColorType: colorType}
+	return ihdr.Bytes()
+}
+
+// Bytes encodes the header as a framed IHDR chunk.
+func (ihdr IHDR) Bytes() []byte {
 	var buf []byte
 
-	buf = append(buf, uint32ToBytesBE(width)...)
-	buf = append(buf, uint32ToBytesBE(height)...)
+	buf = append(buf, uint32ToBytesBE(uint32(ihdr.Width))...)
+	buf = append(buf, uint32ToBytesBE(uint32(ihdr.Height))...)
 
-	// INFO: bit depth 8 or 16?
-	buf = append(buf, 8)
-	// color type
-	buf = append(buf, 2)
-	// compression method
-	buf = append(buf, 0)
-	// filter method
-	buf = append(buf, 0)
-	// interlace method
-	buf = append(buf, 0)
+	buf = append(buf, ihdr.BitDepth)
+	buf = append(buf, byte(ihdr.ColorType))
+	buf = append(buf, ihdr.CompressionMethod)
+	buf = append(buf, ihdr.FilterMethod)
+	buf = append(buf, ihdr.InterlaceMethod)
 
-	return buf
+	return ChunkBytes("IHDR", buf)
 }
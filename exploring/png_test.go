@@ -1,8 +1,9 @@
-package main
+package exploring
 
 import (
 	"bytes"
 	"fmt"
+	"image/png"
 	"testing"
 )
 
@@ -16,26 +17,32 @@ func TestIHDRChunkBytes(t *testing.T) {
 			width:  256,
 			height: 120,
 			expected: []byte{
+				0, 0, 0, 13, // length
+				'I', 'H', 'D', 'R', // chunk type
 				0b0, 0b0, 0b1, 0b0, // width
 				0b0, 0b0, 0b0, 0b01111000, // height
-				0b1000, // bit depth
-				0b10,   // color type
-				0b0,    // compression method
-				0b0,    // filter method
-				0b0,    // interlace method
+				0b1000,                 // bit depth
+				0b10,                   // color type
+				0b0,                    // compression method
+				0b0,                    // filter method
+				0b0,                    // interlace method
+				0x92, 0x16, 0xbb, 0x29, // crc
 			},
 		},
 		{
 			width:  10,
 			height: 6,
 			expected: []byte{
+				0, 0, 0, 13, // length
+				'I', 'H', 'D', 'R', // chunk type
 				0b0, 0b0, 0b0, 0b1010, // width
 				0b0, 0b0, 0b0, 0b110, // height
-				0b1000, // bit depth
-				0b10,   // color type
-				0b0,    // compression method
-				0b0,    // filter method
-				0b0,    // interlace method
+				0b1000,                 // bit depth
+				0b10,                   // color type
+				0b0,                    // compression method
+				0b0,                    // filter method
+				0b0,                    // interlace method
+				0x75, 0x92, 0x98, 0x91, // crc
 			},
 		},
 	}
@@ -43,10 +50,161 @@ func TestIHDRChunkBytes(t *testing.T) {
 	for _, tt := range tests {
 		name := fmt.Sprintf("%dx%d", tt.width, tt.height)
 		t.Run(name, func(t *testing.T) {
-			got := IHDRChunk(tt.width, tt.height)
+			got := IHDRChunk(tt.width, tt.height, 2)
 			if bytes.Compare(tt.expected, got) != 0 {
 				t.Errorf("expected %+v, but got %+v", tt.expected, got)
 			}
 		})
 	}
 }
+
+func testImage(width, height uint32, alpha bool) Image {
+	img := NewImage(width, height)
+	for y := range height {
+		for x := range width {
+			p := Pixel{
+				Red:   uint16(x*40%256) * 257,
+				Green: uint16(y*30%256) * 257,
+				Blue:  uint16((x*y)%256) * 257,
+				Alpha: 0xffff,
+			}
+			if alpha {
+				p.Alpha = uint16((x+y)*20%256) * 257
+			}
+			img.Pixels[y][x] = p
+		}
+	}
+	return img
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, alpha := range []bool{false, true} {
+		t.Run(fmt.Sprintf("alpha=%t", alpha), func(t *testing.T) {
+			img := testImage(7, 5, alpha)
+
+			var buf bytes.Buffer
+			if err := Encode(&buf, img); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := ReadPNG(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if alpha && decoded.IHDR.ColorType != 6 || !alpha && decoded.IHDR.ColorType != 2 {
+				t.Errorf("unexpected color type %d", decoded.IHDR.ColorType)
+			}
+			for y := range 5 {
+				for x := range 7 {
+					if img.Pixels[y][x] != decoded.Image.Pixels[y][x] {
+						t.Fatalf("pixel (%d, %d): expected %v, but got %v",
+							x, y, img.Pixels[y][x], decoded.Image.Pixels[y][x])
+					}
+				}
+			}
+
+			std, err := png.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for y := range 5 {
+				for x := range 7 {
+					c := nrgba64(std.At(x, y))
+					got := Pixel{c.R, c.G, c.B, c.A}
+					if want := img.Pixels[y][x]; want != got && !(want.Alpha == 0 && got.Alpha == 0) {
+						t.Fatalf("image/png pixel (%d, %d): expected %v, but got %v", x, y, want, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncoderFilterStrategies(t *testing.T) {
+	img := testImage(40, 30, true)
+
+	sizes := map[FilterStrategy]int{}
+	for name, strategy := range FILTER_STRATEGIES {
+		var buf bytes.Buffer
+		encoder := Encoder{Filter: strategy, Level: 9}
+		if err := encoder.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		sizes[strategy] = buf.Len()
+
+		decoded, err := ReadPNG(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if decoded.Image.Pixels[29][39] != img.Pixels[29][39] {
+			t.Fatalf("%s: expected %v, but got %v", name, img.Pixels[29][39], decoded.Image.Pixels[29][39])
+		}
+	}
+
+	for strategy, size := range sizes {
+		if sizes[BRUTE_FORCE] > size {
+			t.Errorf("brute force (%d bytes) is larger than strategy %d (%d bytes)",
+				sizes[BRUTE_FORCE], strategy, size)
+		}
+	}
+}
+
+func TestEncodeCorpus(t *testing.T) {
+	for _, file := range Corpus() {
+		t.Run(file.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := file.Encoder.Encode(&buf, file.Image); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := ReadPNG(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.IHDR.ColorType != file.Encoder.ColorType || decoded.IHDR.BitDepth != file.Encoder.BitDepth {
+				t.Fatalf("expected color type %d with bit depth %d, but got %d with %d",
+					file.Encoder.ColorType, file.Encoder.BitDepth, decoded.IHDR.ColorType, decoded.IHDR.BitDepth)
+			}
+			comparePixels(t, buf.Bytes(), decoded.Image)
+
+			// palettes and 16 bit truecolor are lossless
+			lossless := file.Encoder.ColorType == 3 ||
+				file.Encoder.BitDepth == 16 && (file.Encoder.ColorType == 2 || file.Encoder.ColorType == 6)
+			if lossless {
+				for y, row := range file.Image.Pixels {
+					for x, p := range row {
+						if got := decoded.Image.Pixels[y][x]; got != p {
+							t.Fatalf("pixel (%d, %d): expected %v, but got %v", x, y, p, got)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestInterlacedScanlines(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := Encoder{Filter: FILTER_UP, ColorType: 0, BitDepth: 1, Interlace: true}
+	if err := encoder.Encode(&buf, testImage(5, 3, false)); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := ReadPNG(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a 5x3 image has no pixels in pass 3 (index 2), whose rows start at y=4
+	var passes []int
+	for _, s := range decoded.Scanlines {
+		passes = append(passes, s.Pass)
+		if s.Filter != UP {
+			t.Errorf("expected filter up, but got %s", s.Filter)
+		}
+	}
+	expected := []int{0, 1, 3, 4, 5, 5, 6}
+	if fmt.Sprint(passes) != fmt.Sprint(expected) {
+		t.Errorf("expected scanlines of passes %v, but got %v", expected, passes)
+	}
+}
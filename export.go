@@ -0,0 +1,260 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Export is a palette to be written: the colors, the name of the palette
+// and how code exporters name the variables of the colors.
+type Export struct {
+	Name   string
+	Prefix string
+	Naming string
+	Colors RGBColorPairSlice
+}
+
+// EXPORTERS write palette files for design tools and code, picked by file
+// extension.
+var EXPORTERS = map[string]func(w io.Writer, e Export) error{
+	".gpl":  writeGPL,
+	".ase":  writeASE,
+	".txt":  writePaintNET,
+	".kpl":  writeKPL,
+	".pal":  writeJASC,
+	".hex":  writeHexPalette,
+	".css":  writeCSS,
+	".scss": writeSCSS,
+	".less": writeLess,
+	".js":   writeTailwindJS,
+	".json": writeTailwindJSON,
+	".go":   writeGo,
+
+	TOKENS_EXT: writeTokens,
+}
+
+// exportPalette writes the colors to dst in the format of its extension.
+// The palette is named after the image at src.
+func exportPalette(dst, src string, e Export) error {
+	ext := strings.ToLower(filepath.Ext(dst))
+	if strings.HasSuffix(strings.ToLower(dst), TOKENS_EXT) {
+		ext = TOKENS_EXT
+	}
+	export, ok := EXPORTERS[ext]
+	if !ok {
+		exts := slices.Sorted(maps.Keys(EXPORTERS))
+		return fmt.Errorf("unknown palette format %q, use one of %s", ext, strings.Join(exts, ", "))
+	}
+
+	var buf bytes.Buffer
+	e.Name = strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+	if err := export(&buf, e); err != nil {
+		return fmt.Errorf("exporting %s: %v", dst, err)
+	}
+
+	return os.WriteFile(dst, buf.Bytes(), 0o644)
+}
+
+// colorName is the generated name of the i-th color of a palette.
+func colorName(i int, rgb RGB) string {
+	return fmt.Sprintf("Color %d %s", i+1, rgb.asHex())
+}
+
+// writeGPL writes a GIMP palette, which Inkscape reads as well.
+func writeGPL(w io.Writer, e Export) error {
+	fmt.Fprintf(w, "GIMP Palette\nName: %s\nColumns: 8\n#\n", e.Name)
+	for i, cc := range e.Colors {
+		rgb := cc.rgb
+		if _, err := fmt.Fprintf(w, "%3d %3d %3d\t%s\n", rgb.red, rgb.green, rgb.blue, colorName(i, rgb)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Block types of Adobe Swatch Exchange files.
+const (
+	ASE_COLOR       = 0x0001
+	ASE_GROUP_START = 0xc001
+	ASE_GROUP_END   = 0xc002
+)
+
+// aseName encodes a name as the length in UTF-16 code units including the
+// terminating zero, followed by the big endian code units.
+func aseName(name string) []byte {
+	units := append(utf16.Encode([]rune(name)), 0)
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(units)))
+	for _, u := range units {
+		data = binary.BigEndian.AppendUint16(data, u)
+	}
+	return data
+}
+
+func aseBlock(blockType uint16, data []byte) []byte {
+	block := binary.BigEndian.AppendUint16(nil, blockType)
+	block = binary.BigEndian.AppendUint32(block, uint32(len(data)))
+	return append(block, data...)
+}
+
+// writeASE writes an Adobe Swatch Exchange file with a group named after the
+// palette holding every color as an RGB swatch.
+func writeASE(w io.Writer, e Export) error {
+	data := []byte("ASEF")
+	data = binary.BigEndian.AppendUint16(data, 1)
+	data = binary.BigEndian.AppendUint16(data, 0)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(e.Colors)+2))
+
+	data = append(data, aseBlock(ASE_GROUP_START, aseName(e.Name))...)
+	for i, cc := range e.Colors {
+		entry := aseName(colorName(i, cc.rgb))
+		entry = append(entry, "RGB "...)
+		for _, v := range []uint8{cc.rgb.red, cc.rgb.green, cc.rgb.blue} {
+			entry = binary.BigEndian.AppendUint32(entry, math.Float32bits(float32(v)/255))
+		}
+		// global, spot and normal colors are 0, 1 and 2
+		entry = binary.BigEndian.AppendUint16(entry, 2)
+		data = append(data, aseBlock(ASE_COLOR, entry)...)
+	}
+	data = append(data, aseBlock(ASE_GROUP_END, nil)...)
+
+	_, err := w.Write(data)
+	return err
+}
+
+// PAINT_NET_MAX_COLORS is the size of the palette of Paint.NET.
+const PAINT_NET_MAX_COLORS = 96
+
+// writePaintNET writes a Paint.NET palette of AARRGGBB lines.
+func writePaintNET(w io.Writer, e Export) error {
+	if len(e.Colors) > PAINT_NET_MAX_COLORS {
+		return fmt.Errorf("Paint.NET palettes hold at most %d colors, got %d, use --limit",
+			PAINT_NET_MAX_COLORS, len(e.Colors))
+	}
+
+	fmt.Fprintf(w, "; paint.net Palette File\n; Palette Name: %s\n; Colors: %d\n", e.Name, len(e.Colors))
+	for _, cc := range e.Colors {
+		if _, err := fmt.Fprintf(w, "FF%02X%02X%02X\n", cc.rgb.red, cc.rgb.green, cc.rgb.blue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KRITA_COLUMNS is the number of swatches per row of a Krita palette.
+const KRITA_COLUMNS = 8
+
+type kritaColorset struct {
+	XMLName  xml.Name     `xml:"Colorset"`
+	Version  string       `xml:"version,attr"`
+	Name     string       `xml:"name,attr"`
+	Comment  string       `xml:"comment,attr"`
+	Columns  int          `xml:"columns,attr"`
+	Rows     int          `xml:"rows,attr"`
+	ReadOnly bool         `xml:"readonly,attr"`
+	Entries  []kritaEntry `xml:"ColorSetEntry"`
+}
+
+type kritaEntry struct {
+	Name     string `xml:"name,attr"`
+	ID       string `xml:"id,attr"`
+	Spot     bool   `xml:"spot,attr"`
+	BitDepth string `xml:"bitdepth,attr"`
+	RGB      struct {
+		R     float64 `xml:"r,attr"`
+		G     float64 `xml:"g,attr"`
+		B     float64 `xml:"b,attr"`
+		Space string  `xml:"space,attr"`
+	} `xml:"RGB"`
+	Position struct {
+		Row    int `xml:"row,attr"`
+		Column int `xml:"column,attr"`
+	} `xml:"Position"`
+}
+
+// writeKPL writes a Krita palette, a zip archive with the mimetype, the
+// colors in colorset.xml and an empty list of embedded profiles.
+func writeKPL(w io.Writer, e Export) error {
+	colorset := kritaColorset{
+		Version: "1.0",
+		Name:    e.Name,
+		Columns: KRITA_COLUMNS,
+		Rows:    max(1, (len(e.Colors)+KRITA_COLUMNS-1)/KRITA_COLUMNS),
+	}
+	for i, cc := range e.Colors {
+		entry := kritaEntry{
+			Name:     colorName(i, cc.rgb),
+			ID:       fmt.Sprint(i + 1),
+			BitDepth: "U8",
+		}
+		entry.RGB.R = float64(cc.rgb.red) / 255
+		entry.RGB.G = float64(cc.rgb.green) / 255
+		entry.RGB.B = float64(cc.rgb.blue) / 255
+		entry.RGB.Space = "sRGB-elle-V2-srgbtrc.icc"
+		entry.Position.Row = i / KRITA_COLUMNS
+		entry.Position.Column = i % KRITA_COLUMNS
+		colorset.Entries = append(colorset.Entries, entry)
+	}
+
+	colorsetXML, err := xml.MarshalIndent(colorset, "", " ")
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	// the mimetype comes first and uncompressed, like in OpenDocument files
+	files := []struct {
+		name   string
+		method uint16
+		data   []byte
+	}{
+		{"mimetype", zip.Store, []byte("krita/x-colorset")},
+		{"colorset.xml", zip.Deflate, append([]byte(xml.Header), colorsetXML...)},
+		{"profiles.xml", zip.Deflate, []byte(xml.Header + "<Profiles/>\n")},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeJASC writes a JASC (Paint Shop Pro) palette, which Aseprite opens as
+// a .pal file.
+func writeJASC(w io.Writer, e Export) error {
+	fmt.Fprintf(w, "JASC-PAL\r\n0100\r\n%d\r\n", len(e.Colors))
+	for _, cc := range e.Colors {
+		if _, err := fmt.Fprintf(w, "%d %d %d\r\n", cc.rgb.red, cc.rgb.green, cc.rgb.blue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHexPalette writes one rrggbb per line, the .hex format of Lospec that
+// Aseprite opens.
+func writeHexPalette(w io.Writer, e Export) error {
+	for _, cc := range e.Colors {
+		if _, err := fmt.Fprintf(w, "%02x%02x%02x\n", cc.rgb.red, cc.rgb.green, cc.rgb.blue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
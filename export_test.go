@@ -0,0 +1,187 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func testExport() Export {
+	return Export{
+		Name: "shot",
+		Colors: RGBColorPairSlice{
+			{rgb: RGB{0x3a, 0x7b, 0xd5}},
+			{rgb: RGB{255, 255, 255}},
+		},
+	}
+}
+
+func TestTextPalettes(t *testing.T) {
+	tests := []struct {
+		write    func(io.Writer, Export) error
+		expected string
+	}{
+		{writeGPL, "GIMP Palette\nName: shot\nColumns: 8\n#\n" +
+			" 58 123 213\tColor 1 #3A7BD5\n255 255 255\tColor 2 #FFFFFF\n"},
+		{writePaintNET, "; paint.net Palette File\n; Palette Name: shot\n; Colors: 2\nFF3A7BD5\nFFFFFFFF\n"},
+		{writeJASC, "JASC-PAL\r\n0100\r\n2\r\n58 123 213\r\n255 255 255\r\n"},
+		{writeHexPalette, "3a7bd5\nffffff\n"},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := tt.write(&b, testExport()); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.expected {
+			t.Errorf("expected %q, but got %q", tt.expected, got)
+		}
+	}
+}
+
+// readASEName reads a length prefixed, zero terminated UTF-16 name.
+func readASEName(t *testing.T, data []byte) (string, int) {
+	t.Helper()
+	n := int(binary.BigEndian.Uint16(data))
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(data[2+2*i:])
+	}
+	if units[n-1] != 0 {
+		t.Fatalf("expected a zero terminated name, but got %v", units)
+	}
+	return string(utf16.Decode(units[:n-1])), 2 + 2*n
+}
+
+func TestWriteASE(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeASE(&buf, testExport()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if string(data[:4]) != "ASEF" || binary.BigEndian.Uint16(data[4:]) != 1 || binary.BigEndian.Uint16(data[6:]) != 0 {
+		t.Fatalf("expected the ASEF signature and version 1.0, but got % x", data[:8])
+	}
+	count := binary.BigEndian.Uint32(data[8:])
+	if count != 4 {
+		t.Errorf("expected 4 blocks, the group start and end and 2 colors, but got %d", count)
+	}
+
+	var types []uint16
+	var names []string
+	rest := data[12:]
+	for len(rest) > 0 {
+		if len(rest) < 6 {
+			t.Fatalf("expected a block header, but got % x", rest)
+		}
+		blockType, length := binary.BigEndian.Uint16(rest), binary.BigEndian.Uint32(rest[2:])
+		if int(length) > len(rest)-6 {
+			t.Fatalf("block of type %#x claims %d bytes, but only %d are left", blockType, length, len(rest)-6)
+		}
+		block := rest[6 : 6+length]
+		rest = rest[6+length:]
+		types = append(types, blockType)
+
+		switch blockType {
+		case ASE_GROUP_START:
+			name, n := readASEName(t, block)
+			if n != len(block) {
+				t.Errorf("expected the group start to hold only the name, but got %d of %d bytes", n, len(block))
+			}
+			names = append(names, name)
+		case ASE_COLOR:
+			name, n := readASEName(t, block)
+			names = append(names, name)
+			if len(block) != n+4+3*4+2 {
+				t.Fatalf("expected %d bytes for an RGB color, but got %d", n+4+3*4+2, len(block))
+			}
+			if model := string(block[n : n+4]); model != "RGB " {
+				t.Errorf("expected the RGB color model, but got %q", model)
+			}
+			if r := math.Float32frombits(binary.BigEndian.Uint32(block[n+4:])); len(names) == 2 && r != 58.0/255 {
+				t.Errorf("expected red %f, but got %f", 58.0/255, r)
+			}
+			if kind := binary.BigEndian.Uint16(block[len(block)-2:]); kind != 2 {
+				t.Errorf("expected a normal color, but got %d", kind)
+			}
+		case ASE_GROUP_END:
+			if length != 0 {
+				t.Errorf("expected an empty group end, but got %d bytes", length)
+			}
+		}
+	}
+
+	expectedTypes := []uint16{ASE_GROUP_START, ASE_COLOR, ASE_COLOR, ASE_GROUP_END}
+	if len(types) != int(count) || !slices.Equal(types, expectedTypes) {
+		t.Errorf("expected blocks %#x, but got %#x", expectedTypes, types)
+	}
+	expectedNames := []string{"shot", "Color 1 #3A7BD5", "Color 2 #FFFFFF"}
+	if !slices.Equal(names, expectedNames) {
+		t.Errorf("expected names %q, but got %q", expectedNames, names)
+	}
+}
+
+func TestWriteKPL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeKPL(&buf, testExport()); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 3 {
+		t.Fatalf("expected 3 files, but got %d", len(zr.File))
+	}
+
+	mimetype := zr.File[0]
+	if mimetype.Name != "mimetype" || mimetype.Method != zip.Store {
+		t.Fatalf("expected an uncompressed mimetype first, but got %s with method %d", mimetype.Name, mimetype.Method)
+	}
+	// readers sniffing the type expect it right after the first header
+	if offset, err := mimetype.DataOffset(); err != nil || !bytes.HasPrefix(buf.Bytes()[offset:], []byte("krita/x-colorset")) {
+		t.Errorf("expected krita/x-colorset as the first file's data, but got offset %d and %v", offset, err)
+	}
+
+	f, err := zr.Open("colorset.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var colorset kritaColorset
+	if err := xml.NewDecoder(f).Decode(&colorset); err != nil {
+		t.Fatal(err)
+	}
+	if colorset.Name != "shot" || len(colorset.Entries) != 2 || colorset.Entries[1].Name != "Color 2 #FFFFFF" {
+		t.Errorf("unexpected colorset %+v", colorset)
+	}
+	if r := colorset.Entries[0].RGB.R; math.Abs(r-58.0/255) > 1e-9 {
+		t.Errorf("expected red %f, but got %f", 58.0/255, r)
+	}
+}
+
+func TestExportPalette(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "palette.HEX")
+	if err := exportPalette(dst, filepath.Join(dir, "my shot.png"), testExport()); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "3a7bd5\nffffff\n" {
+		t.Errorf("expected the hex palette, but got %q and %v", data, err)
+	}
+
+	if err := exportPalette(filepath.Join(dir, "palette.xyz"), "shot.png", testExport()); err == nil {
+		t.Error("expected an error for an unknown extension")
+	}
+}
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"cmp"
 	"flag"
 	"fmt"
+	"image"
 	"image/png"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/aaronbittel/color-picker/colormanage"
+	"github.com/aaronbittel/color-picker/exploring"
 )
 
 const (
@@ -17,28 +25,146 @@ const (
 	Reset     = "\033[m"
 )
 
+// The coverage bars of the text output are a quarter of the terminal wide,
+// within these bounds.
+const (
+	BAR_MIN_WIDTH = 10
+	BAR_MAX_WIDTH = 50
+)
+
 var SORT_BY = []string{"count", "red", "green", "blue"}
 
+var FORMATS = []string{"text", "json", "csv", "tsv", "plain"}
+
+var DECODERS = map[string]func(io.Reader) (image.Image, error){
+	"standard": png.Decode,
+	"native":   exploring.Decode,
+}
+
 func main() {
 	var (
-		filepath  string
-		sortBy    string
-		limit     int
-		verbose   bool
-		proximity float64
+		filepath    string
+		sortBy      string
+		limit       int
+		verbose     bool
+		proximity   float64
+		colorManage bool
+		spltPath    string
+		decoder     string
+		format      string
+		exportPath  string
+		prefix      string
+		naming      string
+		matchPath   string
+		colorWhen   string
+		notation    string
+		nameTables  string
+		reportPath  string
+		swatchPath  string
+		swatchGrid  string
+		swatchLabel bool
+		minPercent  float64
+		interactive bool
+		copyColor   bool
+		clipboardTo string
+		preview     bool
+		previewOf   int
 	)
 
 	sortUsage := fmt.Sprintf("Sort colors by one of: %s", strings.Join(SORT_BY, ", "))
+	formatUsage := fmt.Sprintf("Output format, one of: %s", strings.Join(FORMATS, ", "))
 
 	flag.StringVar(&filepath, "path", "", "Path to a PNG file (skips Flameshot)")
 	flag.StringVar(&sortBy, "sort", "", sortUsage)
 	flag.IntVar(&limit, "limit", 0, "Limit the number of colors displayed")
 	flag.BoolVar(&verbose, "verbose", false, "Show additional sorting details")
+	flag.Float64Var(&minPercent, "min-percent", 0,
+		"Leave out colors covering less than this percentage of the image")
 	flag.Float64Var(&proximity, "proximity", 15.0,
 		"Group colors within this proximity into an average")
+	flag.BoolVar(&colorManage, "color-manage", false,
+		"Convert colors to sRGB using the color profile embedded in the PNG")
+	flag.StringVar(&decoder, "decoder", "standard",
+		"PNG decoder to use: standard (image/png) or native (the exploring decoder)")
+	flag.StringVar(&spltPath, "splt", "",
+		"Write a copy of the PNG with the colors embedded as an sPLT chunk to this path")
+	flag.StringVar(&format, "format", "text", formatUsage)
+	flag.StringVar(&exportPath, "export", "",
+		"Write the colors as a palette file or code, by extension: "+
+			".gpl, .ase, .txt (Paint.NET), .kpl, .pal (JASC), .hex, .css, .scss, .less, .js and .json (Tailwind), .go")
+	flag.StringVar(&prefix, "export-prefix", "color", "Prefix of the variable names of exported code")
+	flag.StringVar(&naming, "export-naming", "index",
+		fmt.Sprintf("Variable names of exported code, one of: %s", strings.Join(NAMINGS, ", ")))
+	flag.StringVar(&colorWhen, "color", "auto",
+		fmt.Sprintf("Color the output: %s. NO_COLOR turns colors off unless always, auto also if stdout is no terminal", strings.Join(COLOR_WHEN, ", ")))
+	flag.StringVar(&notation, "notation", "",
+		fmt.Sprintf("Color notation of text and plain output, one of: %s", strings.Join(NOTATION_NAMES, ", ")))
+	flag.StringVar(&nameTables, "names", "css,x11",
+		"Show the nearest named color of these comma separated tables: css, x11 "+
+			"or the path of an rgb.txt file like the one of the XKCD color survey, \"\" for none")
+	flag.StringVar(&reportPath, "report", "",
+		"Write a self-contained HTML report with the image, the colors and their contrast to this path")
+	flag.StringVar(&swatchPath, "swatches", "",
+		"Render the colors as an image of swatches to this path, .svg or .png")
+	flag.StringVar(&swatchGrid, "swatches-layout", "strip",
+		fmt.Sprintf("Layout of --swatches, one of: %s", strings.Join(SWATCH_LAYOUTS, ", ")))
+	flag.BoolVar(&swatchLabel, "swatches-labels", false, "Write the hex code and percentage on every swatch")
+	flag.BoolVar(&interactive, "interactive", false,
+		"Browse the colors full-screen, changing the sort, proximity and notation live")
+	flag.BoolVar(&copyColor, "copy", false,
+		"Copy the top color, or the selected one of --interactive, to the clipboard in the --notation")
+	flag.StringVar(&clipboardTo, "clipboard", "auto",
+		fmt.Sprintf("Clipboard of --copy and --interactive, one of: %s", strings.Join(CLIPBOARDS, ", ")))
+	flag.BoolVar(&preview, "preview", false,
+		"Show a downscaled preview of the image above the colors")
+	flag.IntVar(&previewOf, "preview-select", 0,
+		"Outline the pixels of the n-th color in the preview, implies --preview")
+	flag.StringVar(&matchPath, "match", "",
+		"Match the colors to the nearest color token of this design tokens (.tokens.json) file")
 	flag.Parse()
 
-	var err error
+	if !slices.Contains(FORMATS, format) {
+		log.Fatalf("unknown format %s", format)
+	}
+
+	if sortBy != "" && !slices.Contains(SORT_BY, sortBy) {
+		log.Fatalf("unknown sort by %s", sortBy)
+	}
+
+	mode, err := detectColorMode(colorWhen, isTerminal(os.Stdout))
+	if err != nil {
+		log.Fatal(err)
+	}
+	colorMode = mode
+
+	// plain output defaults to lowercase hex, the text output to uppercase
+	plainNotation := NOTATIONS["hex"]
+	if notation != "" {
+		n, ok := NOTATIONS[notation]
+		if !ok {
+			log.Fatalf("unknown notation %s", notation)
+		}
+		colorNotation, plainNotation = n, n
+	}
+
+	decode, ok := DECODERS[decoder]
+	if !ok {
+		log.Fatalf("unknown decoder %s", decoder)
+	}
+
+	names, err := loadNames(nameTables)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var tokens []Token
+
+	if matchPath != "" {
+		tokens, err = loadTokens(matchPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	if filepath == "" {
 		filepath, err = flameshot()
@@ -47,36 +173,196 @@ func main() {
 		}
 	}
 
-	colors, err := getColors(filepath)
+	var profile *colormanage.Profile
+	if colorManage || verbose {
+		profile, err = loadProfile(filepath)
+		if verbose && profile != nil && format == "text" {
+			fmt.Printf("color profile: %s\n", profile)
+		}
+		if err != nil {
+			log.Printf("warning: %v, colors are not converted", err)
+			profile = nil
+		}
+		if !colorManage {
+			profile = nil
+		}
+	}
+
+	colors, bitmap, err := getColors(filepath, decode, profile)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	var groupedColors RGBColorPairSlice
-	if proximity <= 0 {
-		for color := range colors {
-			groupedColors = append(groupedColors, RGBCountPair{
-				rgb:   color,
-				count: 1,
-			})
-		}
-	} else {
-		groupedColors = groupSimilarColors(colors, proximity)
+	groupedColors, groups := groupColors(colors, proximity)
+
+	info := ImageInfo{
+		Path:         filepath,
+		Width:        bitmap.Width,
+		Height:       bitmap.Height,
+		UniqueColors: len(colors),
+	}
+	if profile != nil {
+		info.ColorProfile = profile.String()
 	}
 
 	groupedColors = sort(groupedColors, sortBy)
+	groupedColors = filterPercent(groupedColors, info, minPercent)
 	groupedColors = limitSlice(groupedColors, limit)
 
-	for _, cc := range groupedColors {
-		if verbose {
-			printSortInfo(cc, sortBy)
+	if err := checkPreviewSelect(previewOf, len(groupedColors)); err != nil {
+		log.Fatal(err)
+	}
+
+	if spltPath != "" {
+		if err := writeSPLT(filepath, spltPath, groupedColors); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if exportPath != "" {
+		export := Export{Prefix: prefix, Naming: naming, Colors: groupedColors}
+		if err := exportPalette(exportPath, filepath, export); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if reportPath != "" {
+		err := writeReportFile(reportPath, filepath, info, groupedColors, names, cmp.Or(notation, "HEX"))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if swatchPath != "" {
+		swatches, err := layoutSwatches(groupedColors, info, swatchGrid, swatchLabel)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writeSwatchesFile(swatchPath, swatches); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var clipboard Clipboard
+	if copyColor || interactive {
+		clipboard, err = newClipboard(clipboardTo)
+		if err != nil && copyColor {
+			log.Fatal(err)
+		}
+	}
+
+	if interactive {
+		t := newTUI(colors, info, names, proximity, sortBy, cmp.Or(notation, "HEX"), minPercent, limit)
+		t.clipboard = clipboard
+		t.bitmap = bitmap
+		t.preview = preview || previewOf > 0
+		if err := runInteractive(t, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		if text, ok := t.selectedText(); ok && copyColor {
+			if err := t.clipboard.Copy(text); err != nil {
+				log.Fatal(err)
+			}
+		}
+		return
+	}
+
+	if copyColor {
+		if err := copyTop(clipboard, groupedColors, colorNotation); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch format {
+	case "json":
+		err = writeJSON(os.Stdout, info, groupedColors, sortBy, names, tokens)
+	case "csv":
+		err = writeTable(os.Stdout, ',', info, groupedColors, sortBy, names, tokens)
+	case "tsv":
+		err = writeTable(os.Stdout, '\t', info, groupedColors, sortBy, names, tokens)
+	case "plain":
+		err = writePlain(os.Stdout, groupedColors, plainNotation)
+	default:
+		if preview || previewOf > 0 {
+			printPreview(bitmap, groupedColors, previewOf, groups)
+		}
+		barWidth := min(max(terminalWidth()/4, BAR_MIN_WIDTH), BAR_MAX_WIDTH)
+		for _, cc := range groupedColors {
+			if verbose {
+				printSortInfo(cc, sortBy)
+			}
+			share := percent(cc.pixels, info)
+			b := bar(share, barWidth)
+			line := fmt.Sprintf("%s | %6.2f%% %s%s", cc.rgb.formatColor(), share,
+				swatch(cc.rgb, b), strings.Repeat(" ", barWidth-utf8.RuneCountInString(b)))
+			if names != nil {
+				n, d := nearestName(cc.rgb, names)
+				line += fmt.Sprintf(" ≈ %s (distance %.1f)", n.Name, d)
+			}
+			if tokens != nil {
+				t, d := nearestToken(cc.rgb, tokens)
+				line += fmt.Sprintf(" | %s %s (distance %.1f)", swatch(t.rgb, FullBlock), t.Name, d)
+			}
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+		if tokens != nil {
+			printUnmatched(tokens, groupedColors, max(proximity, 0))
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// copyTop puts the first of the colors on the clipboard in the notation.
+func copyTop(clipboard Clipboard, colors RGBColorPairSlice, notation Notation) error {
+	if len(colors) == 0 {
+		return nil
+	}
+	return clipboard.Copy(notation.format(colors[0].rgb))
+}
+
+// printUnmatched lists the tokens no picked color is within maxDist of.
+func printUnmatched(tokens []Token, colors RGBColorPairSlice, maxDist float64) {
+	unmatched := unmatchedTokens(tokens, colors, maxDist)
+	fmt.Printf("%d of %d tokens matched within distance %.1f\n",
+		len(tokens)-len(unmatched), len(tokens), maxDist)
+	for _, t := range unmatched {
+		fmt.Printf("  missing %s %s %s\n", swatch(t.rgb, FullBlock), t.Name, t.rgb.asHex())
+	}
+}
+
+// checkPreviewSelect reports a --preview-select of n that does not name one
+// of count colors. Zero means no color is selected.
+func checkPreviewSelect(n, count int) error {
+	if n != 0 && (n < 1 || n > count) {
+		if count == 0 {
+			return fmt.Errorf("--preview-select %d, but there are no colors", n)
 		}
-		cc.rgb.printColor()
+		return fmt.Errorf("--preview-select %d out of range, use 1 to %d", n, count)
+	}
+	return nil
+}
+
+// printPreview prints the image above the colors, with the pixels of the
+// n-th color outlined if n is given. n must have passed checkPreviewSelect.
+func printPreview(bitmap Bitmap, colors RGBColorPairSlice, n int, groups map[RGB]int) {
+	if colorMode == COLOR_NONE {
+		log.Printf("warning: the preview needs colors, see --color")
+		return
+	}
+
+	var selected *RGBCountPair
+	if n > 0 {
+		selected = &colors[n-1]
+	}
+	for _, line := range renderPreview(bitmap, terminalWidth(), PREVIEW_MAX_ROWS, selected, groups) {
+		fmt.Println(line)
 	}
 }
 
 func colorPrint(color RGB, msg string) {
-	fmt.Println(colored(color), msg, Reset)
+	fmt.Println(swatch(color, msg))
 }
 
 func flameshot() (string, error) {
@@ -90,38 +376,84 @@ func flameshot() (string, error) {
 	return filepath, nil
 }
 
-func getColors(filepath string) (map[RGB]struct{}, error) {
+func loadProfile(filepath string) (*colormanage.Profile, error) {
 	f, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	img, err := png.Decode(f)
+	return colormanage.Load(f)
+}
+
+// getColors counts the pixels of every color of the image, converted to
+// sRGB with profile if given. The converted pixels are returned as well.
+func getColors(
+	filepath string,
+	decode func(io.Reader) (image.Image, error),
+	profile *colormanage.Profile,
+) (map[RGB]int, Bitmap, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, Bitmap{}, err
+	}
+	defer f.Close()
+
+	img, err := decode(f)
 	if err != nil {
-		return nil, err
+		return nil, Bitmap{}, err
 	}
 
 	rect := img.Bounds()
 	startX, startY, endX, endY := rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y
 
-	colorSet := make(map[RGB]struct{})
+	colorCounts := make(map[RGB]int)
+	bitmap := NewBitmap(rect.Dx(), rect.Dy())
 
 	for y := startY; y < endY; y++ {
 		for x := startX; x < endX; x++ {
-			color := fromColor(img.At(x, y))
-			colorSet[color] = struct{}{}
+			c := img.At(x, y)
+			if profile != nil && !profile.IsSRGB() {
+				c = profile.Convert(c)
+			}
+			color := fromColor(c)
+			colorCounts[color]++
+			bitmap.Set(x-startX, y-startY, color)
 		}
 	}
 
-	return colorSet, nil
+	return colorCounts, bitmap, nil
+}
+
+// groupColors groups the colors within proximity, or keeps every color on
+// its own if proximity is not positive.
+// groupColors groups the colors within proximity of each other. It returns
+// the groups and the group every color was assigned to.
+func groupColors(colorCounts map[RGB]int, proximity float64) (RGBColorPairSlice, map[RGB]int) {
+	if proximity > 0 {
+		return groupSimilarColors(colorCounts, proximity)
+	}
+
+	var groupedColors RGBColorPairSlice
+	groups := make(map[RGB]int, len(colorCounts))
+	for color, pixels := range colorCounts {
+		groups[color] = len(groupedColors)
+		groupedColors = append(groupedColors, RGBCountPair{
+			rgb:    color,
+			count:  1,
+			pixels: pixels,
+			group:  len(groupedColors),
+		})
+	}
+	return groupedColors, groups
 }
 
-func groupSimilarColors(colorSet map[RGB]struct{}, proximity float64) RGBColorPairSlice {
-	groupedColors := make([]RGBCountPair, 0, len(colorSet)/2)
-	visited := make([]bool, len(colorSet), len(colorSet))
+func groupSimilarColors(colorCounts map[RGB]int, proximity float64) (RGBColorPairSlice, map[RGB]int) {
+	groupedColors := make([]RGBCountPair, 0, len(colorCounts)/2)
+	groups := make(map[RGB]int, len(colorCounts))
+	visited := make([]bool, len(colorCounts), len(colorCounts))
 	colors := []RGB{}
-	for c := range colorSet {
+	for c := range colorCounts {
 		colors = append(colors, c)
 	}
 
@@ -129,12 +461,11 @@ func groupSimilarColors(colorSet map[RGB]struct{}, proximity float64) RGBColorPa
 		if visited[i] {
 			continue
 		}
-		grouped := []RGB{}
+		// every group starts with its seed, so colors without neighbours
+		// are kept on their own
+		grouped := []RGB{colors[i]}
+		visited[i] = true
 		for j := range colors {
-			if i == j {
-				continue
-			}
-
 			if visited[j] {
 				continue
 			}
@@ -146,15 +477,19 @@ func groupSimilarColors(colorSet map[RGB]struct{}, proximity float64) RGBColorPa
 			}
 		}
 
-		if len(grouped) > 0 {
-			rgbCount := NewColorCount(grouped)
-			groupedColors = append(groupedColors, rgbCount)
+		rgbCount := NewColorCount(grouped)
+		rgbCount.group = len(groupedColors)
+		for _, c := range grouped {
+			rgbCount.pixels += colorCounts[c]
+			groups[c] = rgbCount.group
 		}
+		groupedColors = append(groupedColors, rgbCount)
 	}
 
-	return RGBColorPairSlice(groupedColors)
+	return RGBColorPairSlice(groupedColors), groups
 }
 
+// sort orders the colors by one of SORT_BY, which main checks sortBy against.
 func sort(groupedColors RGBColorPairSlice, sortBy string) RGBColorPairSlice {
 	switch sortBy {
 	case "count":
@@ -166,13 +501,25 @@ func sort(groupedColors RGBColorPairSlice, sortBy string) RGBColorPairSlice {
 	case "blue":
 		groupedColors.sortByBlue()
 	case "":
-	default:
-		fmt.Printf("unknown sort by %s\n", sortBy)
+		// without a sort the output, and with it the color of --copy and
+		// --preview-select, would be in the random order of the grouping
+		groupedColors.sortByPixels()
 	}
 
 	return groupedColors
 }
 
+// filterPercent leaves out the colors covering less than minPercent of the
+// image.
+func filterPercent(groupedColors RGBColorPairSlice, info ImageInfo, minPercent float64) RGBColorPairSlice {
+	if minPercent <= 0 {
+		return groupedColors
+	}
+	return slices.DeleteFunc(groupedColors, func(cc RGBCountPair) bool {
+		return percent(cc.pixels, info) < minPercent
+	})
+}
+
 func limitSlice(groupedColors RGBColorPairSlice, limit int) RGBColorPairSlice {
 	if limit > 0 {
 		limit = min(limit, len(groupedColors))
@@ -181,15 +528,23 @@ func limitSlice(groupedColors RGBColorPairSlice, limit int) RGBColorPairSlice {
 	return groupedColors
 }
 
-func printSortInfo(cc RGBCountPair, sortBy string) {
+// sortMetric returns the name and value of what the colors are sorted by.
+func sortMetric(cc RGBCountPair, sortBy string) (string, int, bool) {
 	switch sortBy {
 	case "count":
-		fmt.Printf("count: %d\t", cc.count)
+		return "count", cc.count, true
 	case "red":
-		fmt.Printf("redDiff: %d\t", cc.redDiff())
+		return "redDiff", cc.redDiff(), true
 	case "green":
-		fmt.Printf("greenDiff: %d\t", cc.greenDiff())
+		return "greenDiff", cc.greenDiff(), true
 	case "blue":
-		fmt.Printf("blueDiff: %d\t", cc.blueDiff())
+		return "blueDiff", cc.blueDiff(), true
+	}
+	return "", 0, false
+}
+
+func printSortInfo(cc RGBCountPair, sortBy string) {
+	if name, value, ok := sortMetric(cc, sortBy); ok {
+		fmt.Printf("%s: %d\t", name, value)
 	}
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// halvesImage writes a PNG of 10x10 pixels, the left half left and the right
+// half right.
+func halvesImage(t *testing.T, left, right color.RGBA) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := range 10 {
+		for x := range 10 {
+			c := left
+			if x >= 5 {
+				c = right
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "halves.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGroupColorsCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     color.RGBA
+		right    color.RGBA
+		expected []float64
+	}{
+		{"far apart", color.RGBA{0x3a, 0x7b, 0xd5, 0xff}, color.RGBA{0xff, 0xff, 0xff, 0xff}, []float64{50, 50}},
+		{"near", color.RGBA{0x3a, 0x7b, 0xd5, 0xff}, color.RGBA{0x3c, 0x7d, 0xd7, 0xff}, []float64{100}},
+	}
+
+	for _, tt := range tests {
+		path := halvesImage(t, tt.left, tt.right)
+		colors, bitmap, err := getColors(path, png.Decode, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := ImageInfo{Width: bitmap.Width, Height: bitmap.Height}
+
+		grouped, _ := groupColors(colors, 15)
+		grouped = sort(grouped, "count")
+		if len(grouped) != len(tt.expected) {
+			t.Fatalf("%s: expected %d colors, but got %v", tt.name, len(tt.expected), grouped)
+		}
+		sum := 0.0
+		for i, cc := range grouped {
+			if got := percent(cc.pixels, info); got != tt.expected[i] {
+				t.Errorf("%s: expected %.2f%% for %s, but got %.2f%%", tt.name, tt.expected[i], cc.rgb.asHex(), got)
+			}
+			sum += percent(cc.pixels, info)
+		}
+		if math.Abs(sum-100) > 0.01 {
+			t.Errorf("%s: expected the percentages to add up to 100, but got %.2f", tt.name, sum)
+		}
+	}
+}
+
+func TestGroupSimilarColors(t *testing.T) {
+	colors := map[RGB]int{
+		{10, 10, 10}:    3,
+		{12, 12, 12}:    1,
+		{200, 200, 200}: 6,
+	}
+
+	grouped, groups := groupSimilarColors(colors, 5)
+	grouped = sort(grouped, "count")
+	if len(grouped) != 2 {
+		t.Fatalf("expected 2 groups, but got %v", grouped)
+	}
+	if len(groups) != 3 || groups[RGB{10, 10, 10}] != grouped[0].group ||
+		groups[RGB{12, 12, 12}] != grouped[0].group || groups[RGB{200, 200, 200}] != grouped[1].group {
+		t.Errorf("expected the grays assigned to their groups, but got %v for %+v", groups, grouped)
+	}
+	if grouped[0].count != 2 || grouped[0].pixels != 4 {
+		t.Errorf("expected the dark grays grouped with 4 pixels, but got %+v", grouped[0])
+	}
+	if grouped[1].rgb != (RGB{200, 200, 200}) || grouped[1].count != 1 || grouped[1].pixels != 6 {
+		t.Errorf("expected the light gray on its own with 6 pixels, but got %+v", grouped[1])
+	}
+}
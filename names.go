@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The named color tables are in the rgb.txt format of X11: "R G B name"
+// lines and comments starting with "!".
+//
+//go:embed names/*.txt
+var namesFS embed.FS
+
+// NAME_TABLES are the embedded tables --names picks from. Anything else is
+// read as a file.
+var NAME_TABLES = map[string]string{
+	"css": "names/css.txt",
+	"x11": "names/x11.txt",
+}
+
+// NamedColor is a color of a named color table like the CSS named colors.
+type NamedColor struct {
+	Name  string
+	Table string
+	rgb   RGB
+}
+
+// loadNames reads the comma separated tables, either the name of an embedded
+// table or the path of a file in the X11 or XKCD rgb.txt format.
+func loadNames(tables string) ([]NamedColor, error) {
+	var names []NamedColor
+	for _, table := range strings.Split(tables, ",") {
+		table = strings.TrimSpace(table)
+		if table == "" {
+			continue
+		}
+
+		var (
+			data []byte
+			err  error
+		)
+		if file, ok := NAME_TABLES[table]; ok {
+			data, err = namesFS.ReadFile(file)
+		} else {
+			data, err = os.ReadFile(table)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		colors, err := parseNames(bytes.NewReader(data), table)
+		if err != nil {
+			return nil, fmt.Errorf("reading names %s: %v", table, err)
+		}
+		names = append(names, colors...)
+	}
+	return names, nil
+}
+
+// parseNames reads "R G B name" lines like X11's rgb.txt and "name #rrggbb"
+// lines like the rgb.txt of the XKCD color survey. Lines starting with "!"
+// or "#" are comments, as is the "License:" line heading the XKCD file. X11
+// lists every name with spaces in CamelCase as well, so names with spaces
+// are only kept from the XKCD format.
+func parseNames(r io.Reader, table string) ([]NamedColor, error) {
+	var names []NamedColor
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "!") || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "License:") {
+			continue
+		}
+		fields := strings.Fields(text)
+
+		if hex := fields[len(fields)-1]; strings.HasPrefix(hex, "#") {
+			rgb, err := parseHexColor(hex)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			names = append(names, NamedColor{strings.Join(fields[:len(fields)-1], " "), table, rgb})
+			continue
+		}
+
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected \"R G B name\" or \"name #rrggbb\"", line)
+		}
+		if len(fields) > 4 {
+			continue
+		}
+		var c [3]uint8
+		for i := range c {
+			v, err := strconv.ParseUint(fields[i], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			c[i] = uint8(v)
+		}
+		names = append(names, NamedColor{fields[3], table, RGB{c[0], c[1], c[2]}})
+	}
+	return names, scanner.Err()
+}
+
+// nearestName returns the named color closest to rgb. Of equally close
+// names, the first one loaded wins.
+func nearestName(rgb RGB, names []NamedColor) (NamedColor, float64) {
+	best, bestDist := NamedColor{}, math.Inf(1)
+	for _, n := range names {
+		if d := dist(rgb, n.rgb); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best, bestDist
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedNames(t *testing.T) {
+	names, err := loadNames("css,x11")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for _, n := range names {
+		counts[n.Table]++
+		if strings.Contains(n.Name, " ") {
+			t.Errorf("%s: expected no spaces in %q", n.Table, n.Name)
+		}
+	}
+	if counts["css"] != 148 {
+		t.Errorf("expected 148 CSS names, but got %d", counts["css"])
+	}
+	if counts["x11"] == 0 {
+		t.Error("expected X11 names")
+	}
+}
+
+func TestNearestName(t *testing.T) {
+	names, err := loadNames("css,x11")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rgb      RGB
+		expected string
+		table    string
+	}{
+		// css comes first, so its names win over equal X11 ones
+		{RGB{65, 105, 225}, "royalblue", "css"},
+		{RGB{0, 128, 0}, "green", "css"},
+		{RGB{0, 255, 0}, "lime", "css"},
+		{RGB{160, 32, 240}, "purple", "x11"},
+		{RGB{0x3a, 0x7b, 0xd5}, "royalblue", "css"},
+	}
+
+	for _, tt := range tests {
+		n, _ := nearestName(tt.rgb, names)
+		if n.Name != tt.expected || n.Table != tt.table {
+			t.Errorf("%v: expected %s from %s, but got %s from %s", tt.rgb, tt.expected, tt.table, n.Name, n.Table)
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	// the first lines of https://xkcd.com/color/rgb.txt, with their
+	// trailing tabs
+	input := "License: http://creativecommons.org/publicdomain/zero/1.0/\n" +
+		"cloudy blue\t#acc2d9\t\n" +
+		"dark pastel green\t#56ae57\t\n" + `
+! a comment
+255 250 250		snow
+248 248 255		ghost white
+`
+	names, err := parseNames(strings.NewReader(input), "xkcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []NamedColor{
+		{"cloudy blue", "xkcd", RGB{0xac, 0xc2, 0xd9}},
+		{"dark pastel green", "xkcd", RGB{0x56, 0xae, 0x57}},
+		{"snow", "xkcd", RGB{255, 250, 250}},
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %d names, but got %v", len(expected), names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected %v, but got %v", expected[i], names[i])
+		}
+	}
+
+	if _, err := parseNames(strings.NewReader("1 2 snow\n"), "bad"); err == nil {
+		t.Error("expected an error for a line without a name")
+	}
+}
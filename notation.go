@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Notation writes a color in one syntax and reads it back.
+type Notation struct {
+	format func(RGB) string
+	parse  func(string) (RGB, error)
+}
+
+var NOTATIONS = map[string]Notation{
+	"hex":    {lowerHex, parseHexColor},
+	"HEX":    {RGB.asHex, parseHexColor},
+	"hexa":   {hexAlpha, parseHexColor},
+	"rgb":    {asRGBFunc, parseRGBFunc},
+	"rgba":   {asRGBAFunc, parseRGBFunc},
+	"hsl":    {asHSL, parseHSL},
+	"hwb":    {asHWB, parseHWB},
+	"oklch":  {asOKLCh, parseOKLCh},
+	"0x":     {as0x, parse0x},
+	"go":     {asGo, parseGo},
+	"swift":  {asSwift, parseSwift},
+	"kotlin": {asKotlin, parseKotlin},
+}
+
+// NOTATION_NAMES lists the notations in the order they are documented.
+var NOTATION_NAMES = []string{"hex", "HEX", "hexa", "rgb", "rgba", "hsl", "hwb", "oklch", "0x", "go", "swift", "kotlin"}
+
+// colorNotation is the notation of the text output, set from --notation.
+var colorNotation = NOTATIONS["HEX"]
+
+// parseColor reads a color in any of the notations.
+func parseColor(s string) (RGB, error) {
+	s = strings.TrimSpace(s)
+	for _, name := range NOTATION_NAMES {
+		if rgb, err := NOTATIONS[name].parse(s); err == nil {
+			return rgb, nil
+		}
+	}
+	return RGB{}, fmt.Errorf("unknown color notation %q", s)
+}
+
+// lowerHex is the color as #rrggbb, the way stylesheets usually spell it.
+func lowerHex(rgb RGB) string {
+	return fmt.Sprintf("#%02x%02x%02x", rgb.red, rgb.green, rgb.blue)
+}
+
+func hexAlpha(rgb RGB) string {
+	return lowerHex(rgb) + "ff"
+}
+
+// parseHexColor reads #rgb, #rrggbb and #rrggbbaa, ignoring the alpha.
+func parseHexColor(s string) (RGB, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok {
+		return RGB{}, fmt.Errorf("color %q does not start with #", s)
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 && len(hex) != 8 {
+		return RGB{}, fmt.Errorf("color %q is not #rgb, #rrggbb or #rrggbbaa", s)
+	}
+	if len(hex) == 8 {
+		if _, err := strconv.ParseUint(hex[6:], 16, 8); err != nil {
+			return RGB{}, fmt.Errorf("color %q has an alpha that is not 2 hexadecimal digits", s)
+		}
+	}
+	return parseHexDigits(hex[:6])
+}
+
+func parseHexDigits(hex string) (RGB, error) {
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return RGB{}, fmt.Errorf("color %q is not 6 hexadecimal digits", hex)
+	}
+	return RGB{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+func as0x(rgb RGB) string {
+	return fmt.Sprintf("0x%02X%02X%02X", rgb.red, rgb.green, rgb.blue)
+}
+
+func parse0x(s string) (RGB, error) {
+	hex, ok := strings.CutPrefix(strings.ToLower(s), "0x")
+	if !ok {
+		return RGB{}, fmt.Errorf("color %q does not start with 0x", s)
+	}
+	return parseHexDigits(hex)
+}
+
+// funcArgs splits a CSS-like function call such as "rgb(1, 2, 3)" or
+// "hsl(120 50% 50% / 0.5)" into its arguments. The name is matched without
+// regard to case.
+func funcArgs(s string, names ...string) ([]string, error) {
+	open := strings.IndexByte(s, '(')
+	if open < 0 || !strings.HasSuffix(s, ")") ||
+		!slices.Contains(names, strings.ToLower(strings.TrimSpace(s[:open]))) {
+		return nil, fmt.Errorf("color %q is not one of %s()", s, strings.Join(names, "(), "))
+	}
+
+	return strings.FieldsFunc(s[open+1:len(s)-1], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '/'
+	}), nil
+}
+
+// number reads a plain number or a percentage, which is scaled to 0..scale.
+func number(arg string, scale float64) (float64, error) {
+	if p, ok := strings.CutSuffix(arg, "%"); ok {
+		v, err := strconv.ParseFloat(p, 64)
+		return v / 100 * scale, err
+	}
+	return strconv.ParseFloat(strings.TrimSuffix(arg, "deg"), 64)
+}
+
+// numbers reads the first n arguments, each with its own scale for
+// percentages. More arguments than that are an alpha value.
+func numbers(args []string, scales ...float64) ([]float64, error) {
+	if len(args) != len(scales) && len(args) != len(scales)+1 {
+		return nil, fmt.Errorf("expected %d values, got %d", len(scales), len(args))
+	}
+	values := make([]float64, len(scales))
+	for i, scale := range scales {
+		v, err := number(args[i], scale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", args[i])
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// channel rounds and clamps a value of 0..255.
+func channel(v float64) uint8 {
+	return uint8(math.Round(min(max(v, 0), 255)))
+}
+
+func asRGBFunc(rgb RGB) string {
+	return fmt.Sprintf("rgb(%d, %d, %d)", rgb.red, rgb.green, rgb.blue)
+}
+
+func asRGBAFunc(rgb RGB) string {
+	return fmt.Sprintf("rgba(%d, %d, %d, 1)", rgb.red, rgb.green, rgb.blue)
+}
+
+func parseRGBFunc(s string) (RGB, error) {
+	args, err := funcArgs(s, "rgb", "rgba")
+	if err != nil {
+		return RGB{}, err
+	}
+	v, err := numbers(args, 255, 255, 255)
+	if err != nil {
+		return RGB{}, fmt.Errorf("color %q: %v", s, err)
+	}
+	return RGB{channel(v[0]), channel(v[1]), channel(v[2])}, nil
+}
+
+// trim formats v with up to prec decimals, without trailing zeros.
+func trim(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func asHSL(rgb RGB) string {
+	c := rgb.HSL()
+	return fmt.Sprintf("hsl(%s %s%% %s%%)", trim(c.H, 1), trim(c.S*100, 1), trim(c.L*100, 1))
+}
+
+func parseHSL(s string) (RGB, error) {
+	args, err := funcArgs(s, "hsl", "hsla")
+	if err != nil {
+		return RGB{}, err
+	}
+	v, err := numbers(args, 360, 1, 1)
+	if err != nil {
+		return RGB{}, fmt.Errorf("color %q: %v", s, err)
+	}
+	return HSL{v[0], v[1], v[2]}.RGB(), nil
+}
+
+func asHWB(rgb RGB) string {
+	c := rgb.HWB()
+	return fmt.Sprintf("hwb(%s %s%% %s%%)", trim(c.H, 1), trim(c.W*100, 1), trim(c.B*100, 1))
+}
+
+func parseHWB(s string) (RGB, error) {
+	args, err := funcArgs(s, "hwb")
+	if err != nil {
+		return RGB{}, err
+	}
+	v, err := numbers(args, 360, 1, 1)
+	if err != nil {
+		return RGB{}, fmt.Errorf("color %q: %v", s, err)
+	}
+	return HWB{v[0], v[1], v[2]}.RGB(), nil
+}
+
+func asOKLCh(rgb RGB) string {
+	c := rgb.OKLCh()
+	// grays have no hue, rounding noise would otherwise show up
+	if c.C < 0.000005 {
+		c.C, c.H = 0, 0
+	}
+	return fmt.Sprintf("oklch(%s%% %s %s)", trim(c.L*100, 3), trim(c.C, 5), trim(c.H, 3))
+}
+
+func parseOKLCh(s string) (RGB, error) {
+	args, err := funcArgs(s, "oklch")
+	if err != nil {
+		return RGB{}, err
+	}
+	// 100% chroma is 0.4 in CSS Color 4
+	v, err := numbers(args, 1, 0.4, 360)
+	if err != nil {
+		return RGB{}, fmt.Errorf("color %q: %v", s, err)
+	}
+	if !strings.HasSuffix(args[0], "%") && v[0] > 1 {
+		return RGB{}, fmt.Errorf("color %q: lightness %v is not between 0 and 1", s, v[0])
+	}
+	return OKLCh{v[0], v[1], v[2]}.RGB(), nil
+}
+
+func asGo(rgb RGB) string {
+	return fmt.Sprintf("color.RGBA{R: 0x%02x, G: 0x%02x, B: 0x%02x, A: 0xff}", rgb.red, rgb.green, rgb.blue)
+}
+
+func parseGo(s string) (RGB, error) {
+	var r, g, b, a uint8
+	if _, err := fmt.Sscanf(s, "color.RGBA{R: %v, G: %v, B: %v, A: %v}", &r, &g, &b, &a); err != nil {
+		return RGB{}, fmt.Errorf("color %q is not a color.RGBA literal", s)
+	}
+	return RGB{r, g, b}, nil
+}
+
+// asSwift writes a SwiftUI color. Three decimals are enough to get back the
+// same 8 bit channels.
+func asSwift(rgb RGB) string {
+	return fmt.Sprintf("Color(red: %s, green: %s, blue: %s)",
+		trim(float64(rgb.red)/255, 3), trim(float64(rgb.green)/255, 3), trim(float64(rgb.blue)/255, 3))
+}
+
+func parseSwift(s string) (RGB, error) {
+	var r, g, b float64
+	if _, err := fmt.Sscanf(s, "Color(red: %g, green: %g, blue: %g)", &r, &g, &b); err != nil {
+		return RGB{}, fmt.Errorf("color %q is not a SwiftUI color", s)
+	}
+	return RGB{channel(r * 255), channel(g * 255), channel(b * 255)}, nil
+}
+
+// asKotlin writes a Jetpack Compose color, which takes 0xAARRGGBB.
+func asKotlin(rgb RGB) string {
+	return fmt.Sprintf("Color(0xFF%02X%02X%02X)", rgb.red, rgb.green, rgb.blue)
+}
+
+func parseKotlin(s string) (RGB, error) {
+	hex, ok := strings.CutPrefix(s, "Color(0x")
+	if !ok || !strings.HasSuffix(hex, ")") || len(hex) != 9 {
+		return RGB{}, fmt.Errorf("color %q is not a Compose color", s)
+	}
+	return parseHexDigits(hex[2:8])
+}
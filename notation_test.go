@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAsHexPadding(t *testing.T) {
+	tests := []struct {
+		rgb      RGB
+		expected string
+	}{
+		{RGB{1, 2, 3}, "#010203"},
+		{RGB{222, 140, 8}, "#DE8C08"},
+		{RGB{0, 0, 0}, "#000000"},
+		{RGB{255, 255, 255}, "#FFFFFF"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rgb.asHex(); got != tt.expected {
+			t.Errorf("expected %s, but got %s", tt.expected, got)
+		}
+	}
+}
+
+func TestNotationFormats(t *testing.T) {
+	rgb := RGB{110, 35, 30}
+	expected := map[string]string{
+		"hex":    "#6e231e",
+		"HEX":    "#6E231E",
+		"hexa":   "#6e231eff",
+		"rgb":    "rgb(110, 35, 30)",
+		"rgba":   "rgba(110, 35, 30, 1)",
+		"hsl":    "hsl(3.8 57.1% 27.5%)",
+		"hwb":    "hwb(3.8 11.8% 56.9%)",
+		"oklch":  "oklch(37.122% 0.10756 27.366)",
+		"0x":     "0x6E231E",
+		"go":     "color.RGBA{R: 0x6e, G: 0x23, B: 0x1e, A: 0xff}",
+		"swift":  "Color(red: 0.431, green: 0.137, blue: 0.118)",
+		"kotlin": "Color(0xFF6E231E)",
+	}
+
+	for _, name := range NOTATION_NAMES {
+		if got := NOTATIONS[name].format(rgb); got != expected[name] {
+			t.Errorf("%s: expected %s, but got %s", name, expected[name], got)
+		}
+	}
+}
+
+// every formatter has to give back the same color through its parser, for
+// a grid through the whole RGB cube
+func TestNotationRoundTrip(t *testing.T) {
+	var steps []uint8
+	for v := 0; v < 256; v += 5 {
+		steps = append(steps, uint8(v))
+	}
+	steps = append(steps, 1, 2, 127, 128, 254)
+
+	for _, name := range NOTATION_NAMES {
+		notation := NOTATIONS[name]
+		failures := 0
+		for _, r := range steps {
+			for _, g := range steps {
+				for _, b := range steps {
+					rgb := RGB{r, g, b}
+					s := notation.format(rgb)
+					got, err := notation.parse(s)
+					if err != nil || got != rgb {
+						t.Errorf("%s: %v formats as %s, parsed as %v (%v)", name, rgb, s, got, err)
+						failures++
+					}
+					if failures > 5 {
+						t.FailNow()
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected RGB
+	}{
+		{"#abc", RGB{0xaa, 0xbb, 0xcc}},
+		{"#AABBCC80", RGB{0xaa, 0xbb, 0xcc}},
+		{"rgb(1 2 3 / 50%)", RGB{1, 2, 3}},
+		{"RGBA(100%, 0%, 50%, 0.5)", RGB{255, 0, 128}},
+		{"hsl(120deg 100% 50%)", RGB{0, 255, 0}},
+		{"hwb(0 100% 100%)", RGB{128, 128, 128}},
+		{"oklch(0.628 0.2577 29.23)", RGB{255, 0, 0}},
+		{"0xff8000", RGB{255, 128, 0}},
+		{"Color(0x80FF0000)", RGB{255, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseColor(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %v, but got %v", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"rgb(1, 2)", "#112233zz", "#11223", "#1122zz"} {
+		if _, err := parseColor(input); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
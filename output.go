@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ImageInfo describes the image the colors were picked from.
+type ImageInfo struct {
+	Path         string `json:"path"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	UniqueColors int    `json:"unique_colors"`
+	ColorProfile string `json:"color_profile,omitempty"`
+}
+
+type jsonMetric struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+type jsonMatch struct {
+	Token    string  `json:"token"`
+	Hex      string  `json:"hex"`
+	Distance float64 `json:"distance"`
+}
+
+type jsonName struct {
+	Name     string  `json:"name"`
+	Table    string  `json:"table"`
+	Hex      string  `json:"hex"`
+	Distance float64 `json:"distance"`
+}
+
+type jsonColor struct {
+	RGB     [3]uint8    `json:"rgb"`
+	Hex     string      `json:"hex"`
+	Count   int         `json:"count"`
+	Pixels  int         `json:"pixels"`
+	Percent float64     `json:"percent"`
+	Metric  *jsonMetric `json:"metric,omitempty"`
+	Name    *jsonName   `json:"name,omitempty"`
+	Match   *jsonMatch  `json:"match,omitempty"`
+}
+
+type jsonOutput struct {
+	ImageInfo
+	Colors []jsonColor `json:"colors"`
+}
+
+// percent is the share of the image's pixels, rounded to two decimals.
+func percent(pixels int, info ImageInfo) float64 {
+	total := info.Width * info.Height
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(pixels)/float64(total)*10000) / 100
+}
+
+// PARTIAL_BLOCKS are the left eighths of a FullBlock, from one to seven.
+var PARTIAL_BLOCKS = []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// bar draws percent of 100 as FullBlocks and a partial block in at most
+// width columns.
+func bar(percent float64, width int) string {
+	eighths := int(math.Round(min(max(percent, 0), 100) / 100 * float64(width*8)))
+	b := strings.Repeat(FullBlock, eighths/8)
+	if eighths%8 > 0 {
+		b += PARTIAL_BLOCKS[eighths%8-1]
+	}
+	return b
+}
+
+// writeJSON writes the image info and the colors as a single JSON object.
+// Colors are named after the nearest of names and matched to the nearest of
+// tokens, if given.
+func writeJSON(w io.Writer, info ImageInfo, colors RGBColorPairSlice, sortBy string, names []NamedColor, tokens []Token) error {
+	out := jsonOutput{ImageInfo: info, Colors: make([]jsonColor, 0, len(colors))}
+
+	for _, cc := range colors {
+		c := jsonColor{
+			RGB:     [3]uint8{cc.rgb.red, cc.rgb.green, cc.rgb.blue},
+			Hex:     cc.rgb.asHex(),
+			Count:   cc.count,
+			Pixels:  cc.pixels,
+			Percent: percent(cc.pixels, info),
+		}
+		if name, value, ok := sortMetric(cc, sortBy); ok {
+			c.Metric = &jsonMetric{name, value}
+		}
+		if names != nil {
+			n, d := nearestName(cc.rgb, names)
+			c.Name = &jsonName{n.Name, n.Table, n.rgb.asHex(), math.Round(d*100) / 100}
+		}
+		if tokens != nil {
+			t, d := nearestToken(cc.rgb, tokens)
+			c.Match = &jsonMatch{t.Name, t.rgb.asHex(), math.Round(d*100) / 100}
+		}
+		out.Colors = append(out.Colors, c)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeTable writes one row per color after a header row, separated by
+// comma. The sort metric, the nearest of names and the nearest of tokens
+// follow as the last columns if the colors are sorted or names or tokens are
+// given.
+func writeTable(w io.Writer, comma rune, info ImageInfo, colors RGBColorPairSlice, sortBy string, names []NamedColor, tokens []Token) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	header := []string{"red", "green", "blue", "hex", "count", "pixels", "percent"}
+	if name, _, ok := sortMetric(RGBCountPair{}, sortBy); ok {
+		header = append(header, name)
+	}
+	if names != nil {
+		header = append(header, "name", "name_distance")
+	}
+	if tokens != nil {
+		header = append(header, "token", "token_distance")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, cc := range colors {
+		record := []string{
+			strconv.Itoa(int(cc.rgb.red)),
+			strconv.Itoa(int(cc.rgb.green)),
+			strconv.Itoa(int(cc.rgb.blue)),
+			cc.rgb.asHex(),
+			strconv.Itoa(cc.count),
+			strconv.Itoa(cc.pixels),
+			strconv.FormatFloat(percent(cc.pixels, info), 'f', 2, 64),
+		}
+		if _, value, ok := sortMetric(cc, sortBy); ok {
+			record = append(record, strconv.Itoa(value))
+		}
+		if names != nil {
+			n, d := nearestName(cc.rgb, names)
+			record = append(record, n.Name, strconv.FormatFloat(d, 'f', 2, 64))
+		}
+		if tokens != nil {
+			t, d := nearestToken(cc.rgb, tokens)
+			record = append(record, t.Name, strconv.FormatFloat(d, 'f', 2, 64))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writePlain writes one color per line in the given notation.
+func writePlain(w io.Writer, colors RGBColorPairSlice, notation Notation) error {
+	for _, cc := range colors {
+		if _, err := fmt.Fprintln(w, notation.format(cc.rgb)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
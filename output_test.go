@@ -0,0 +1,203 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		percent  float64
+		width    int
+		expected string
+	}{
+		{0, 10, ""},
+		{100, 4, "████"},
+		{50, 4, "██"},
+		{6.45, 20, "█▎"},
+		{0.5, 10, ""},
+		{1, 10, "▏"},
+		{2, 10, "▎"},
+		{150, 3, "███"},
+	}
+
+	for _, tt := range tests {
+		got := bar(tt.percent, tt.width)
+		if got != tt.expected {
+			t.Errorf("%.2f%% of %d: expected %q, but got %q", tt.percent, tt.width, tt.expected, got)
+		}
+		if n := utf8.RuneCountInString(got); n > tt.width {
+			t.Errorf("%.2f%% of %d: expected at most %d columns, but got %d", tt.percent, tt.width, tt.width, n)
+		}
+	}
+}
+
+func TestFilterPercent(t *testing.T) {
+	info := ImageInfo{Width: 10, Height: 10}
+	colors := RGBColorPairSlice{
+		{rgb: RGB{1, 1, 1}, pixels: 50},
+		{rgb: RGB{2, 2, 2}, pixels: 4},
+		{rgb: RGB{3, 3, 3}, pixels: 5},
+	}
+
+	got := filterPercent(colors, info, 5)
+	if len(got) != 2 || got[0].rgb != (RGB{1, 1, 1}) || got[1].rgb != (RGB{3, 3, 3}) {
+		t.Errorf("expected the colors of 50 and 5 pixels, but got %v", got)
+	}
+}
+
+// testColors groups two near blues and white, each half of a 10x10 image.
+func testColors() (ImageInfo, RGBColorPairSlice) {
+	colors := map[RGB]int{
+		{58, 123, 213}:  30,
+		{60, 125, 215}:  20,
+		{255, 255, 255}: 50,
+	}
+	info := ImageInfo{Path: "shot.png", Width: 10, Height: 10, UniqueColors: len(colors)}
+	grouped, _ := groupColors(colors, 15)
+	return info, sort(grouped, "count")
+}
+
+func TestWriteJSON(t *testing.T) {
+	info, colors := testColors()
+	names := []NamedColor{{"royalblue", "css", RGB{65, 105, 225}}, {"white", "css", RGB{255, 255, 255}}}
+
+	var b strings.Builder
+	if err := writeJSON(&b, info, colors, "count", names, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "path": "shot.png",
+  "width": 10,
+  "height": 10,
+  "unique_colors": 3,
+  "colors": [
+    {
+      "rgb": [
+        59,
+        124,
+        214
+      ],
+      "hex": "#3B7CD6",
+      "count": 2,
+      "pixels": 50,
+      "percent": 50,
+      "metric": {
+        "name": "count",
+        "value": 2
+      },
+      "name": {
+        "name": "royalblue",
+        "table": "css",
+        "hex": "#4169E1",
+        "distance": 22.76
+      }
+    },
+    {
+      "rgb": [
+        255,
+        255,
+        255
+      ],
+      "hex": "#FFFFFF",
+      "count": 1,
+      "pixels": 50,
+      "percent": 50,
+      "metric": {
+        "name": "count",
+        "value": 1
+      },
+      "name": {
+        "name": "white",
+        "table": "css",
+        "hex": "#FFFFFF",
+        "distance": 0
+      }
+    }
+  ]
+}
+`
+	if got := b.String(); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+}
+
+func TestMinPercentCoverage(t *testing.T) {
+	// the two near reds cover 25% together, the lone blue 5%
+	colors := map[RGB]int{
+		{250, 0, 0}:     15,
+		{245, 5, 5}:     10,
+		{0, 0, 250}:     5,
+		{255, 255, 255}: 70,
+	}
+	info := ImageInfo{Width: 10, Height: 10}
+
+	tests := []struct {
+		minPercent float64
+		expected   []RGB
+	}{
+		{5, []RGB{{255, 255, 255}, {247, 2, 2}, {0, 0, 250}}},
+		{5.01, []RGB{{255, 255, 255}, {247, 2, 2}}},
+		{25, []RGB{{255, 255, 255}, {247, 2, 2}}},
+		{25.01, []RGB{{255, 255, 255}}},
+	}
+
+	for _, tt := range tests {
+		grouped, _ := groupColors(colors, 15)
+		slices.SortFunc(grouped, func(a, b RGBCountPair) int { return b.pixels - a.pixels })
+		got := filterPercent(grouped, info, tt.minPercent)
+
+		var rgbs []RGB
+		for _, cc := range got {
+			rgbs = append(rgbs, cc.rgb)
+		}
+		if !slices.Equal(rgbs, tt.expected) {
+			t.Errorf("--min-percent %.2f: expected %v, but got %v", tt.minPercent, tt.expected, rgbs)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	info, colors := testColors()
+	tokens := []Token{{"brand.primary", RGB{0x3a, 0x7b, 0xd5}}}
+
+	tests := []struct {
+		comma    rune
+		sortBy   string
+		tokens   []Token
+		expected string
+	}{
+		{',', "", nil, "red,green,blue,hex,count,pixels,percent\n" +
+			"59,124,214,#3B7CD6,2,50,50.00\n" +
+			"255,255,255,#FFFFFF,1,50,50.00\n"},
+		{'\t', "red", tokens, "red\tgreen\tblue\thex\tcount\tpixels\tpercent\tredDiff\ttoken\ttoken_distance\n" +
+			"59\t124\t214\t#3B7CD6\t2\t50\t50.00\t-220\tbrand.primary\t1.73\n" +
+			"255\t255\t255\t#FFFFFF\t1\t50\t50.00\t0\tbrand.primary\t240.83\n"},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := writeTable(&b, tt.comma, info, colors, tt.sortBy, nil, tt.tokens); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.expected {
+			t.Errorf("expected\n%s\nbut got\n%s", tt.expected, got)
+		}
+	}
+}
+
+func TestWritePlain(t *testing.T) {
+	_, colors := testColors()
+
+	var b strings.Builder
+	if err := writePlain(&b, colors, NOTATIONS["rgb"]); err != nil {
+		t.Fatal(err)
+	}
+	expected := "rgb(59, 124, 214)\nrgb(255, 255, 255)\n"
+	if got := b.String(); got != expected {
+		t.Errorf("expected %q, but got %q", expected, got)
+	}
+}
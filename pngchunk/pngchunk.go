@@ -0,0 +1,132 @@
+package pngchunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var Signature = []byte{137, 80, 78, 71, 13, 10, 26, 10}
+
+var ErrSignature = errors.New("pngchunk: not a png file")
+
+// MaxLength is the largest chunk length the PNG specification allows.
+const MaxLength = 1<<31 - 1
+
+// Chunk is a raw PNG chunk. The length and crc are derived from Type and
+// Data when the chunk is written.
+type Chunk struct {
+	Type string
+	Data []byte
+}
+
+// Critical reports whether the chunk must be understood by a decoder. The
+// case of the first letter of the type carries that bit.
+func (c Chunk) Critical() bool {
+	return len(c.Type) == 4 && c.Type[0]&0x20 == 0
+}
+
+// ReadAll reads the signature and every chunk up to and including IEND.
+func ReadAll(r io.Reader) ([]Chunk, error) {
+	var chunks []Chunk
+	err := Walk(r, func(c Chunk) bool {
+		chunks = append(chunks, c)
+		return true
+	})
+	return chunks, err
+}
+
+// Walk reads the signature and calls fn for every chunk until IEND is
+// reached or fn returns false.
+func Walk(r io.Reader, fn func(Chunk) bool) error {
+	signature := make([]byte, len(Signature))
+	if _, err := io.ReadFull(r, signature); err != nil {
+		return fmt.Errorf("pngchunk: reading signature: %v", err)
+	}
+	if !bytes.Equal(signature, Signature) {
+		return ErrSignature
+	}
+
+	for {
+		c, err := Read(r)
+		if err != nil {
+			return err
+		}
+		if !fn(c) || c.Type == "IEND" {
+			return nil
+		}
+	}
+}
+
+// Read reads one chunk and checks its crc.
+func Read(r io.Reader) (Chunk, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Chunk{}, fmt.Errorf("pngchunk: reading chunk header: %v", err)
+	}
+
+	// The length is not trusted before the crc is checked, so the data
+	// grows as it is read instead of being allocated up front.
+	size := binary.BigEndian.Uint32(header[:4])
+	if size > MaxLength {
+		return Chunk{}, fmt.Errorf("pngchunk: chunk length %d out of range", size)
+	}
+
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+		return Chunk{}, fmt.Errorf("pngchunk: reading %d bytes of %s: %v",
+			size, header[4:], err)
+	}
+
+	var crc [4]byte
+	if _, err := io.ReadFull(r, crc[:]); err != nil {
+		return Chunk{}, fmt.Errorf("pngchunk: reading crc of %s: %v", header[4:], err)
+	}
+
+	c := Chunk{Type: string(header[4:]), Data: data.Bytes()}
+	if got, want := c.CRC(), binary.BigEndian.Uint32(crc[:]); got != want {
+		return Chunk{}, fmt.Errorf("pngchunk: crc mismatch in %s chunk: got %08x, want %08x",
+			c.Type, got, want)
+	}
+
+	return c, nil
+}
+
+// CRC computes the checksum over the chunk type and data.
+func (c Chunk) CRC() uint32 {
+	h := crc32.NewIEEE()
+	h.Write([]byte(c.Type))
+	h.Write(c.Data)
+	return h.Sum32()
+}
+
+// Bytes returns the framed chunk: length, type, data and crc.
+func (c Chunk) Bytes() []byte {
+	buf := make([]byte, 0, 12+len(c.Data))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(c.Data)))
+	buf = append(buf, c.Type...)
+	buf = append(buf, c.Data...)
+	return binary.BigEndian.AppendUint32(buf, c.CRC())
+}
+
+// WriteTo writes the framed chunk.
+func (c Chunk) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(c.Bytes())
+	return int64(n), err
+}
+
+// WriteAll writes the signature followed by all chunks.
+func WriteAll(w io.Writer, chunks []Chunk) error {
+	if _, err := w.Write(Signature); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if _, err := c.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package pngchunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadAllRoundTrip(t *testing.T) {
+	chunks := []Chunk{
+		{Type: "IHDR", Data: []byte{0, 0, 0, 1, 0, 0, 0, 1, 8, 2, 0, 0, 0}},
+		{Type: "tEXt", Data: []byte("Comment\x00hello")},
+		{Type: "IEND"},
+	}
+	var buf bytes.Buffer
+	if err := WriteAll(&buf, chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(chunks) {
+		t.Fatalf("expected %d chunks, but got %d", len(chunks), len(got))
+	}
+	for i := range chunks {
+		if got[i].Type != chunks[i].Type || !bytes.Equal(got[i].Data, chunks[i].Data) {
+			t.Errorf("expected %s %q, but got %s %q", chunks[i].Type, chunks[i].Data, got[i].Type, got[i].Data)
+		}
+	}
+}
+
+func TestReadLength(t *testing.T) {
+	header := func(size uint32) []byte {
+		data := append([]byte{}, Signature...)
+		data = binary.BigEndian.AppendUint32(data, size)
+		return append(data, "IDAT"...)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"out of range", header(1 << 31), "out of range"},
+		{"truncated", append(header(MaxLength), "only a few bytes"...), "reading 2147483647 bytes of IDAT"},
+	}
+
+	for _, tt := range tests {
+		// a claimed length must not be allocated before the data is there
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := ReadAll(bytes.NewReader(tt.data))
+		runtime.ReadMemStats(&after)
+
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected an error with %q, but got %v", tt.name, tt.expected, err)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("%s: expected less than 1 MiB to be allocated, but got %d bytes", tt.name, n)
+		}
+	}
+}
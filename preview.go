@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+)
+
+// UpperHalfBlock shows two pixels in one cell, the upper one in the
+// foreground and the lower one in the background color.
+const UpperHalfBlock = "▀"
+
+// PREVIEW_MAX_ROWS limits the height of the preview in terminal rows.
+const PREVIEW_MAX_ROWS = 24
+
+// OUTLINE_COLORS are drawn around the pixels of the selected color, the one
+// most different from it is used.
+var OUTLINE_COLORS = []RGB{{255, 0, 255}, {0, 255, 255}, {255, 255, 0}}
+
+// Bitmap holds the colors of the pixels of an image, row by row.
+type Bitmap struct {
+	Width, Height int
+	pixels        []RGB
+}
+
+func NewBitmap(width, height int) Bitmap {
+	return Bitmap{width, height, make([]RGB, width*height)}
+}
+
+func (b Bitmap) At(x, y int) RGB {
+	return b.pixels[y*b.Width+x]
+}
+
+func (b Bitmap) Set(x, y int, rgb RGB) {
+	b.pixels[y*b.Width+x] = rgb
+}
+
+// previewPixel is a pixel of the downscaled image, with whether any of the
+// pixels it covers is selected.
+type previewPixel struct {
+	rgb      RGB
+	selected bool
+}
+
+// downscale averages boxes of scale by scale pixels. selected tells which
+// pixels belong to the selection, it may be nil.
+func downscale(b Bitmap, scale int, selected func(RGB) bool) ([][]previewPixel, int, int) {
+	width := (b.Width + scale - 1) / scale
+	height := (b.Height + scale - 1) / scale
+
+	rows := make([][]previewPixel, height)
+	for py := range height {
+		rows[py] = make([]previewPixel, width)
+		for px := range width {
+			var sum [3]int
+			n := 0
+			p := &rows[py][px]
+			for y := py * scale; y < min((py+1)*scale, b.Height); y++ {
+				for x := px * scale; x < min((px+1)*scale, b.Width); x++ {
+					c := b.At(x, y)
+					sum[0] += int(c.red)
+					sum[1] += int(c.green)
+					sum[2] += int(c.blue)
+					n++
+					if selected != nil && selected(c) {
+						p.selected = true
+					}
+				}
+			}
+			p.rgb = RGB{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n)}
+		}
+	}
+	return rows, width, height
+}
+
+// outline colors the pixels next to a selected pixel, but not selected
+// themselves, in rgb.
+func outline(rows [][]previewPixel, rgb RGB) {
+	var border [][2]int
+	for y, row := range rows {
+		for x, p := range row {
+			if p.selected {
+				continue
+			}
+			for _, d := range [][2]int{{0, -1}, {0, 1}, {-1, 0}, {1, 0}} {
+				nx, ny := x+d[0], y+d[1]
+				if ny >= 0 && ny < len(rows) && nx >= 0 && nx < len(row) && rows[ny][nx].selected {
+					border = append(border, [2]int{x, y})
+					break
+				}
+			}
+		}
+	}
+	for _, p := range border {
+		rows[p[1]][p[0]].rgb = rgb
+	}
+}
+
+// outlineColor is the one of OUTLINE_COLORS farthest from rgb.
+func outlineColor(rgb RGB) RGB {
+	best := OUTLINE_COLORS[0]
+	for _, c := range OUTLINE_COLORS[1:] {
+		if dist(rgb, c) > dist(rgb, best) {
+			best = c
+		}
+	}
+	return best
+}
+
+// renderPreview draws the bitmap in at most cols by rows terminal cells,
+// two pixels per cell. If selected is given, the pixels whose color groups
+// assigned to it are outlined.
+func renderPreview(b Bitmap, cols, rows int, selected *RGBCountPair, groups map[RGB]int) []string {
+	if b.Width == 0 || b.Height == 0 || cols <= 0 || rows <= 0 {
+		return nil
+	}
+	scale := max((b.Width+cols-1)/cols, (b.Height+2*rows-1)/(2*rows), 1)
+
+	var belongs func(RGB) bool
+	if selected != nil {
+		belongs = func(c RGB) bool {
+			group, ok := groups[c]
+			return ok && group == selected.group
+		}
+	}
+	pixels, width, height := downscale(b, scale, belongs)
+	if selected != nil {
+		outline(pixels, outlineColor(selected.rgb))
+	}
+
+	var lines []string
+	for y := 0; y < height; y += 2 {
+		var line strings.Builder
+		for x := range width {
+			line.WriteString(colored(pixels[y][x].rgb))
+			if y+1 < height {
+				line.WriteString(coloredBackground(pixels[y+1][x].rgb))
+			}
+			line.WriteString(UpperHalfBlock)
+		}
+		line.WriteString(Reset)
+		lines = append(lines, line.String())
+	}
+	return lines
+}
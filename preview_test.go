@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDownscale(t *testing.T) {
+	b := NewBitmap(3, 2)
+	b.Set(0, 0, RGB{100, 0, 0})
+	b.Set(1, 0, RGB{200, 0, 0})
+	b.Set(0, 1, RGB{0, 100, 0})
+	b.Set(1, 1, RGB{0, 100, 40})
+	b.Set(2, 1, RGB{9, 9, 9})
+
+	rows, width, height := downscale(b, 2, func(c RGB) bool { return c == RGB{9, 9, 9} })
+	if width != 2 || height != 1 {
+		t.Fatalf("expected 2x1, but got %dx%d", width, height)
+	}
+	if got := rows[0][0]; got.rgb != (RGB{75, 50, 10}) || got.selected {
+		t.Errorf("expected the unselected average {75 50 10}, but got %v", got)
+	}
+	if got := rows[0][1]; got.rgb != (RGB{4, 4, 4}) || !got.selected {
+		t.Errorf("expected the selected average {4 4 4}, but got %v", got)
+	}
+}
+
+func TestOutline(t *testing.T) {
+	rows := make([][]previewPixel, 3)
+	for y := range rows {
+		rows[y] = make([]previewPixel, 3)
+	}
+	rows[1][1].selected = true
+
+	outline(rows, RGB{255, 0, 255})
+
+	expected := []string{
+		".#.",
+		"#.#",
+		".#.",
+	}
+	for y, row := range rows {
+		var got strings.Builder
+		for _, p := range row {
+			if p.rgb == (RGB{255, 0, 255}) {
+				got.WriteString("#")
+			} else {
+				got.WriteString(".")
+			}
+		}
+		if got.String() != expected[y] {
+			t.Errorf("row %d: expected %s, but got %s", y, expected[y], got.String())
+		}
+	}
+}
+
+func TestRenderPreview(t *testing.T) {
+	b := NewBitmap(40, 30)
+
+	// 40 columns fit, 30 pixels need 15 rows, so the 10 rows halve the size
+	lines := renderPreview(b, 80, 10, nil, nil)
+	if len(lines) != 8 {
+		t.Fatalf("expected 8 lines, but got %d", len(lines))
+	}
+	if n := strings.Count(lines[0], UpperHalfBlock); n != 20 {
+		t.Errorf("expected 20 cells, but got %d", n)
+	}
+}
+
+// the selection follows the group assignment, not the distance to the
+// color of the group
+func TestRenderPreviewSelected(t *testing.T) {
+	red, black := RGB{200, 0, 0}, RGB{0, 0, 0}
+	b := NewBitmap(3, 1)
+	b.Set(0, 0, red)
+	b.Set(1, 0, black)
+	b.Set(2, 0, black)
+
+	selected := RGBCountPair{rgb: black, group: 1}
+	groups := map[RGB]int{red: 1, black: 2}
+
+	lines := renderPreview(b, 3, 1, &selected, groups)
+	expected := colored(red) + UpperHalfBlock + colored(outlineColor(black)) + UpperHalfBlock +
+		colored(black) + UpperHalfBlock + Reset
+	if len(lines) != 1 || lines[0] != expected {
+		t.Errorf("expected the red pixel selected and outlined, but got %q", lines)
+	}
+}
+
+func TestCheckPreviewSelect(t *testing.T) {
+	tests := []struct {
+		n, count int
+		expected string
+	}{
+		{0, 0, ""},
+		{1, 3, ""},
+		{3, 3, ""},
+		{4, 3, "--preview-select 4 out of range, use 1 to 3"},
+		{-1, 3, "--preview-select -1 out of range, use 1 to 3"},
+		{1, 0, "--preview-select 1, but there are no colors"},
+	}
+	for _, tt := range tests {
+		err := checkPreviewSelect(tt.n, tt.count)
+		if got := fmt.Sprint(err); (err == nil) != (tt.expected == "") || (err != nil && got != tt.expected) {
+			t.Errorf("%d of %d: expected %q, but got %v", tt.n, tt.count, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//go:embed templates/report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+// REPORT_CONTRAST_COLORS limits the contrast table, which grows with the
+// square of the colors.
+const REPORT_CONTRAST_COLORS = 16
+
+// Report is everything the HTML report shows. The source image is embedded
+// as a data URL, so the report is a single file.
+type Report struct {
+	Name         string
+	Generated    string
+	Info         ImageInfo
+	Image        template.URL
+	NotationName string
+	Colors       []reportColor
+	Contrast     reportContrast
+	Truncated    bool
+}
+
+type reportColor struct {
+	Hex      string
+	RGB      string
+	Notation string
+	Name     string
+	Count    int
+	Pixels   int
+	Percent  string
+	// Text is black or white, whichever is more readable on the color.
+	Text string
+}
+
+type reportContrast struct {
+	Colors []reportColor
+	Rows   []reportContrastRow
+}
+
+type reportContrastRow struct {
+	Color reportColor
+	Cells []reportContrastCell
+}
+
+type reportContrastCell struct {
+	Ratio      string
+	Level      string
+	Label      string
+	Foreground string
+	Background string
+}
+
+// writeReportFile writes the report of the colors of the image at src to dst,
+// with the colors in the named notation besides hex and rgb.
+func writeReportFile(dst, src string, info ImageInfo, colors RGBColorPairSlice, names []NamedColor, notation string) error {
+	img, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	r := newReport(info, img, colors, names, NOTATIONS[notation])
+	r.Name = filepath.Base(src)
+	r.NotationName = notation
+
+	var buf bytes.Buffer
+	if err := writeReport(&buf, r); err != nil {
+		return fmt.Errorf("writing report %s: %v", dst, err)
+	}
+	return os.WriteFile(dst, buf.Bytes(), 0o644)
+}
+
+// newReport collects the colors, their nearest names and the contrast of
+// every pair of the first REPORT_CONTRAST_COLORS colors.
+func newReport(info ImageInfo, img []byte, colors RGBColorPairSlice, names []NamedColor, notation Notation) Report {
+	r := Report{
+		Generated: time.Now().Format(time.DateTime),
+		Info:      info,
+		Image:     template.URL("data:" + http.DetectContentType(img) + ";base64," + base64.StdEncoding.EncodeToString(img)),
+	}
+
+	for _, cc := range colors {
+		c := reportColor{
+			Hex:      cc.rgb.asHex(),
+			RGB:      asRGBFunc(cc.rgb),
+			Notation: notation.format(cc.rgb),
+			Count:    cc.count,
+			Pixels:   cc.pixels,
+			Percent:  fmt.Sprintf("%.2f", percent(cc.pixels, info)),
+			Text:     readableText(cc.rgb),
+		}
+		if names != nil {
+			n, d := nearestName(cc.rgb, names)
+			c.Name = fmt.Sprintf("%s (distance %.1f)", n.Name, d)
+		}
+		r.Colors = append(r.Colors, c)
+	}
+
+	compared := colors
+	if len(compared) > REPORT_CONTRAST_COLORS {
+		compared = compared[:REPORT_CONTRAST_COLORS]
+		r.Truncated = true
+	}
+	r.Contrast.Colors = r.Colors[:len(compared)]
+	for i, fg := range compared {
+		row := reportContrastRow{Color: r.Colors[i]}
+		for _, bg := range compared {
+			ratio := contrastRatio(fg.rgb, bg.rgb)
+			level, label := wcagLevel(ratio)
+			row.Cells = append(row.Cells, reportContrastCell{
+				Ratio:      fmt.Sprintf("%.2f", ratio),
+				Level:      level,
+				Label:      label,
+				Foreground: fg.rgb.asHex(),
+				Background: bg.rgb.asHex(),
+			})
+		}
+		r.Contrast.Rows = append(r.Contrast.Rows, row)
+	}
+
+	return r
+}
+
+func writeReport(w io.Writer, r Report) error {
+	return reportTemplate.Execute(w, r)
+}
+
+// luminance is the relative luminance of WCAG 2.
+func luminance(rgb RGB) float64 {
+	c := rgb.Linear()
+	return 0.2126*c.R + 0.7152*c.G + 0.0722*c.B
+}
+
+// contrastRatio is the WCAG 2 contrast ratio of two colors, from 1 to 21.
+func contrastRatio(a, b RGB) float64 {
+	la, lb := luminance(a), luminance(b)
+	return (max(la, lb) + 0.05) / (min(la, lb) + 0.05)
+}
+
+// wcagLevel returns the CSS class and the description of the highest WCAG
+// level text with this contrast ratio passes.
+func wcagLevel(ratio float64) (string, string) {
+	switch {
+	case ratio >= 7:
+		return "aaa", "AAA"
+	case ratio >= 4.5:
+		return "aa", "AA, AAA for large text"
+	case ratio >= 3:
+		return "aa-large", "AA for large text only"
+	default:
+		return "fail", "fails AA"
+	}
+}
+
+// readableText returns black or white, whichever contrasts more with rgb.
+func readableText(rgb RGB) string {
+	if contrastRatio(rgb, RGB{0, 0, 0}) >= contrastRatio(rgb, RGB{255, 255, 255}) {
+		return "#000000"
+	}
+	return "#FFFFFF"
+}
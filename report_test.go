@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		a, b     RGB
+		expected float64
+	}{
+		{RGB{0, 0, 0}, RGB{255, 255, 255}, 21},
+		{RGB{255, 255, 255}, RGB{0, 0, 0}, 21},
+		{RGB{119, 119, 119}, RGB{255, 255, 255}, 4.48},
+		{RGB{0, 0, 255}, RGB{255, 255, 255}, 8.59},
+		{RGB{40, 40, 40}, RGB{40, 40, 40}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := contrastRatio(tt.a, tt.b); math.Abs(got-tt.expected) > 0.005 {
+			t.Errorf("%v on %v: expected %.2f, but got %.2f", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	info := ImageInfo{Path: "shot.png", Width: 2, Height: 2, UniqueColors: 2}
+	colors := RGBColorPairSlice{
+		{rgb: RGB{0x3a, 0x7b, 0xd5}, count: 1, pixels: 3},
+		{rgb: RGB{255, 255, 255}, count: 1, pixels: 1},
+	}
+	img := []byte("\x89PNG\r\n\x1a\n")
+
+	r := newReport(info, img, colors, nil, NOTATIONS["hsl"])
+	r.Name, r.NotationName = "shot.png", "hsl"
+	var b strings.Builder
+	if err := writeReport(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+
+	for _, expected := range []string{
+		`src="data:image/png;base64,iVBORw0KGgo="`,
+		`data-copy="#3A7BD5"`,
+		`data-copy="rgb(58, 123, 213)"`,
+		`data-copy="hsl(214.8 64.9% 53.1%)"`,
+		"75.00%",
+		"4.22",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected the report to contain %s", expected)
+		}
+	}
+	if strings.Contains(html, "http://") || strings.Contains(html, "https://") {
+		t.Error("expected no external references")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
 	"image/color"
 	"math"
@@ -17,8 +18,13 @@ var (
 type ColorSet map[RGB]struct{}
 
 type RGBCountPair struct {
-	rgb   RGB
-	count int
+	rgb RGB
+	// count is the number of unique colors grouped together, pixels the
+	// number of pixels they cover.
+	count  int
+	pixels int
+	// group identifies the colors assigned to the pair by groupColors.
+	group int
 }
 
 func NewColorCount(rgbs []RGB) RGBCountPair {
@@ -67,6 +73,19 @@ func (cps *RGBColorPairSlice) sortByCount() {
 	})
 }
 
+// sortByPixels puts the colors covering the most pixels first. Ties are
+// ordered by their rgb value, so the order does not depend on the grouping.
+func (cps *RGBColorPairSlice) sortByPixels() {
+	slices.SortFunc(*cps, func(a, b RGBCountPair) int {
+		return cmp.Or(
+			cmp.Compare(b.pixels, a.pixels),
+			cmp.Compare(a.rgb.red, b.rgb.red),
+			cmp.Compare(a.rgb.green, b.rgb.green),
+			cmp.Compare(a.rgb.blue, b.rgb.blue),
+		)
+	})
+}
+
 func (cp *RGBColorPairSlice) sortByRed() {
 	slices.SortFunc(*cp, func(a, b RGBCountPair) int {
 		aRDiff := a.redDiff()
@@ -125,7 +144,7 @@ func fromColor(color color.Color) RGB {
 }
 
 func (rgb RGB) asHex() string {
-	return fmt.Sprintf("#%X%X%X", rgb.red, rgb.green, rgb.blue)
+	return fmt.Sprintf("#%02X%02X%02X", rgb.red, rgb.green, rgb.blue)
 }
 
 func (rgb RGB) asFormattedRGB() string {
@@ -133,12 +152,42 @@ func (rgb RGB) asFormattedRGB() string {
 }
 
 func (rgb RGB) printColor() {
-	colorBlock := fmt.Sprintf("%s%s%s", colored(rgb), strings.Repeat(FullBlock, 5), Reset)
-	fmt.Printf("%s %s | %s\n", colorBlock, rgb.asFormattedRGB(), rgb.asHex())
+	fmt.Println(rgb.formatColor())
+}
+
+func (rgb RGB) formatColor() string {
+	colorBlock := swatch(rgb, strings.Repeat(FullBlock, 5))
+	return fmt.Sprintf("%s %s | %s", colorBlock, rgb.asFormattedRGB(), colorNotation.format(rgb))
 }
 
+// colored is the escape sequence for the foreground color rgb, reduced to
+// what the terminal supports.
 func colored(rgb RGB) string {
-	return fmt.Sprintf("\033[38;2;%d;%d;%dm", rgb.red, rgb.green, rgb.blue)
+	return colorEscape(rgb, 30, 38)
+}
+
+// coloredBackground is the escape sequence for the background color rgb.
+func coloredBackground(rgb RGB) string {
+	return colorEscape(rgb, 40, 48)
+}
+
+// colorEscape picks the SGR sequence of the color mode, base is the code of
+// the first of the 8 basic colors and extended the one of 256 and truecolor.
+func colorEscape(rgb RGB, base, extended int) string {
+	switch colorMode {
+	case COLOR_NONE:
+		return ""
+	case COLOR_16:
+		i := ansi16(rgb)
+		if i < 8 {
+			return fmt.Sprintf("\033[%dm", base+i)
+		}
+		return fmt.Sprintf("\033[%dm", base+60+i-8)
+	case COLOR_256:
+		return fmt.Sprintf("\033[%d;5;%dm", extended, xterm256(rgb))
+	default:
+		return fmt.Sprintf("\033[%d;2;%d;%d;%dm", extended, rgb.red, rgb.green, rgb.blue)
+	}
 }
 
 type ColorType int
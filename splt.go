@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/aaronbittel/color-picker/pngchunk"
+)
+
+const SPLT_NAME = "color-picker"
+
+// spltChunk encodes the colors as a suggested palette with a sample depth of
+// 8. The count of each color is its frequency, scaled down if it does not fit
+// into 16 bits.
+func spltChunk(name string, colors RGBColorPairSlice) pngchunk.Chunk {
+	maxCount := 0
+	for _, cc := range colors {
+		maxCount = max(maxCount, cc.count)
+	}
+
+	data := append([]byte(name), 0, 8)
+	for _, cc := range colors {
+		frequency := cc.count
+		if maxCount > 0xffff {
+			frequency = cc.count * 0xffff / maxCount
+		}
+		data = append(data, cc.rgb.red, cc.rgb.green, cc.rgb.blue, 255)
+		data = binary.BigEndian.AppendUint16(data, uint16(frequency))
+	}
+
+	return pngchunk.Chunk{Type: "sPLT", Data: data}
+}
+
+// writeSPLT copies the png at src to dst and adds the colors as an sPLT
+// chunk in front of the image data. An existing sPLT chunk of the same name
+// is replaced.
+func writeSPLT(src, dst string, colors RGBColorPairSlice) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	chunks, err := pngchunk.ReadAll(in)
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", src, err)
+	}
+
+	splt := spltChunk(SPLT_NAME, colors)
+	prefix := append([]byte(SPLT_NAME), 0)
+
+	var out []pngchunk.Chunk
+	inserted := false
+	for _, c := range chunks {
+		if c.Type == "sPLT" && len(c.Data) >= len(prefix) && string(c.Data[:len(prefix)]) == string(prefix) {
+			continue
+		}
+		if c.Type == "IDAT" && !inserted {
+			out = append(out, splt)
+			inserted = true
+		}
+		out = append(out, c)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if err := pngchunk.WriteAll(f, out); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
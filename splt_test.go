@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/binary"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+func TestSPLTChunk(t *testing.T) {
+	colors := RGBColorPairSlice{
+		{rgb: RGB{0x3a, 0x7b, 0xd5}, count: 200000},
+		{rgb: RGB{255, 255, 255}, count: 50000},
+	}
+	c := spltChunk("test", colors)
+
+	if c.Type != "sPLT" {
+		t.Fatalf("expected an sPLT chunk, but got %s", c.Type)
+	}
+	if string(c.Data[:5]) != "test\x00" || c.Data[5] != 8 {
+		t.Fatalf("expected the name, a NUL and sample depth 8, but got % x", c.Data[:6])
+	}
+
+	entries := c.Data[6:]
+	if len(entries) != 6*len(colors) {
+		t.Fatalf("expected %d entries of 6 bytes, but got %d bytes", len(colors), len(entries))
+	}
+	// the frequencies are scaled to fit into 16 bits
+	expected := []struct {
+		rgba      [4]byte
+		frequency uint16
+	}{
+		{[4]byte{0x3a, 0x7b, 0xd5, 255}, 0xffff},
+		{[4]byte{255, 255, 255, 255}, 0xffff / 4},
+	}
+	for i, e := range expected {
+		entry := entries[6*i : 6*i+6]
+		if [4]byte(entry[:4]) != e.rgba || binary.BigEndian.Uint16(entry[4:]) != e.frequency {
+			t.Errorf("entry %d: expected % x with frequency %d, but got % x", i, e.rgba, e.frequency, entry)
+		}
+	}
+}
+
+func TestWriteSPLT(t *testing.T) {
+	src := halvesImage(t, color.RGBA{0x3a, 0x7b, 0xd5, 255}, color.RGBA{255, 255, 255, 255})
+	dst := filepath.Join(t.TempDir(), "out.png")
+	colors := RGBColorPairSlice{{rgb: RGB{0x3a, 0x7b, 0xd5}, count: 50}, {rgb: RGB{255, 255, 255}, count: 50}}
+
+	// writing twice replaces the palette instead of adding a second one
+	if err := writeSPLT(src, dst, colors); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSPLT(dst, dst, colors[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	png, err := exploring.ReadPNG(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(png.Suggested) != 1 {
+		t.Fatalf("expected 1 suggested palette, but got %d", len(png.Suggested))
+	}
+	splt := png.Suggested[0]
+	if splt.Name != SPLT_NAME || splt.SampleDepth != 8 || len(splt.Entries) != 1 {
+		t.Fatalf("unexpected palette %+v", splt)
+	}
+	if e := splt.Entries[0]; e.Color.Red != 0x3a*257 || e.Color.Alpha != 0xffff || e.Frequency != 50 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
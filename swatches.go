@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aaronbittel/color-picker/exploring"
+)
+
+// SWATCH_LAYOUTS are the arrangements of --swatches: a strip with widths
+// proportional to the pixels of every color, or a grid of equal cells.
+var SWATCH_LAYOUTS = []string{"strip", "grid"}
+
+// Sizes of the swatch images in pixels.
+const (
+	STRIP_WIDTH  = 1200
+	STRIP_HEIGHT = 160
+	GRID_COLUMNS = 8
+	GRID_CELL    = 150
+)
+
+// SWATCH_WRITERS render the swatches, picked by file extension.
+var SWATCH_WRITERS = map[string]func(w io.Writer, s Swatches) error{
+	".svg": writeSwatchesSVG,
+	".png": writeSwatchesPNG,
+}
+
+// Swatches are the colors laid out as rectangles of an image.
+type Swatches struct {
+	Width, Height int
+	Rects         []swatchRect
+}
+
+type swatchRect struct {
+	x, y, w, h int
+	rgb        RGB
+	pixels     int
+	// labels are the lines written on the swatch, none if it is too small.
+	labels []string
+}
+
+// layoutSwatches arranges the colors in the given layout. With labels, every
+// swatch wide enough gets its hex code and percentage of the image.
+func layoutSwatches(colors RGBColorPairSlice, info ImageInfo, layout string, labels bool) (Swatches, error) {
+	var s Swatches
+	switch layout {
+	case "strip":
+		weight := func(cc RGBCountPair) int { return cc.pixels }
+		total := 0
+		for _, cc := range colors {
+			total += cc.pixels
+		}
+		if total == 0 {
+			weight, total = func(RGBCountPair) int { return 1 }, len(colors)
+		}
+		s.Width, s.Height = STRIP_WIDTH, STRIP_HEIGHT
+
+		// rounding the running sum makes the widths add up to the strip
+		x, sum := 0, 0
+		for _, cc := range colors {
+			sum += weight(cc)
+			end := (STRIP_WIDTH*sum + total/2) / total
+			if end > x {
+				s.Rects = append(s.Rects, swatchRect{x: x, y: 0, w: end - x, h: STRIP_HEIGHT, rgb: cc.rgb, pixels: cc.pixels})
+			}
+			x = end
+		}
+	case "grid":
+		columns := min(GRID_COLUMNS, max(len(colors), 1))
+		rows := (len(colors) + columns - 1) / columns
+		s.Width, s.Height = columns*GRID_CELL, max(rows, 1)*GRID_CELL
+		for i, cc := range colors {
+			s.Rects = append(s.Rects, swatchRect{
+				x: i % columns * GRID_CELL, y: i / columns * GRID_CELL,
+				w: GRID_CELL, h: GRID_CELL, rgb: cc.rgb, pixels: cc.pixels,
+			})
+		}
+	default:
+		return Swatches{}, fmt.Errorf("unknown swatch layout %q, use one of %s", layout, strings.Join(SWATCH_LAYOUTS, ", "))
+	}
+
+	if labels {
+		for i := range s.Rects {
+			r := &s.Rects[i]
+			lines := []string{r.rgb.asHex(), fmt.Sprintf("%.2f%%", percent(r.pixels, info))}
+			if r.w >= textWidth(lines[0])+2*LABEL_PADDING {
+				r.labels = lines
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// writeSwatchesFile renders the swatches to dst in the format of its
+// extension.
+func writeSwatchesFile(dst string, s Swatches) error {
+	ext := strings.ToLower(filepath.Ext(dst))
+	write, ok := SWATCH_WRITERS[ext]
+	if !ok {
+		exts := slices.Sorted(maps.Keys(SWATCH_WRITERS))
+		return fmt.Errorf("unknown swatch format %q, use one of %s", ext, strings.Join(exts, ", "))
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, s); err != nil {
+		return fmt.Errorf("writing swatches %s: %v", dst, err)
+	}
+	return os.WriteFile(dst, buf.Bytes(), 0o644)
+}
+
+func writeSwatchesSVG(w io.Writer, s Swatches) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		s.Width, s.Height, s.Width, s.Height)
+	for _, r := range s.Rects {
+		fmt.Fprintf(&b, "  <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", r.x, r.y, r.w, r.h, lowerHex(r.rgb))
+		for i, line := range r.labels {
+			y := r.y + r.h - LABEL_PADDING - (len(r.labels)-1-i)*LINE_HEIGHT
+			fmt.Fprintf(&b, "  <text x=\"%d\" y=\"%d\" fill=\"%s\" font-family=\"monospace\" font-size=\"%d\">%s</text>\n",
+				r.x+LABEL_PADDING, y, readableText(r.rgb), GLYPH_HEIGHT*GLYPH_SCALE, line)
+		}
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeSwatchesPNG draws the swatches and their labels in the bitmap font
+// and encodes them with the exploring encoder.
+func writeSwatchesPNG(w io.Writer, s Swatches) error {
+	img := exploring.NewImage(uint32(s.Width), uint32(s.Height))
+	for _, r := range s.Rects {
+		fillRect(img, r.x, r.y, r.w, r.h, r.rgb)
+
+		text, _ := parseHexColor(readableText(r.rgb))
+		for i, line := range r.labels {
+			bottom := r.y + r.h - LABEL_PADDING - (len(r.labels)-1-i)*LINE_HEIGHT
+			drawText(img, r.x+LABEL_PADDING, bottom-GLYPH_HEIGHT*GLYPH_SCALE, line, text)
+		}
+	}
+	return exploring.Encode(w, img)
+}
+
+func fillRect(img exploring.Image, x, y, w, h int, rgb RGB) {
+	p := exploring.Pixel{
+		Red:   uint16(rgb.red) * 257,
+		Green: uint16(rgb.green) * 257,
+		Blue:  uint16(rgb.blue) * 257,
+		Alpha: 0xffff,
+	}
+	for py := max(y, 0); py < min(y+h, int(img.Height)); py++ {
+		for px := max(x, 0); px < min(x+w, int(img.Width)); px++ {
+			img.Pixels[py][px] = p
+		}
+	}
+}
+
+// Metrics of the bitmap font, whose 3x5 glyphs are drawn GLYPH_SCALE times
+// as large.
+const (
+	GLYPH_WIDTH   = 3
+	GLYPH_HEIGHT  = 5
+	GLYPH_SCALE   = 3
+	GLYPH_ADVANCE = (GLYPH_WIDTH + 1) * GLYPH_SCALE
+	LINE_HEIGHT   = (GLYPH_HEIGHT + 2) * GLYPH_SCALE
+	LABEL_PADDING = 8
+)
+
+// GLYPHS is a font of the characters of hex codes and percentages.
+var GLYPHS = map[rune][GLYPH_HEIGHT]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "###", "#..", "###"},
+	'F': {"###", "#..", "###", "#..", "#.."},
+	'#': {"#.#", "###", "#.#", "###", "#.#"},
+	'.': {"...", "...", "...", "...", ".#."},
+	'%': {"#.#", "..#", ".#.", "#..", "#.#"},
+	' ': {"...", "...", "...", "...", "..."},
+}
+
+func textWidth(text string) int {
+	return len(text)*GLYPH_ADVANCE - GLYPH_SCALE
+}
+
+// drawText draws text with its top left corner at x, y. Characters missing
+// from GLYPHS are left blank.
+func drawText(img exploring.Image, x, y int, text string, rgb RGB) {
+	for _, r := range text {
+		glyph := GLYPHS[r]
+		for gy, row := range glyph {
+			for gx, dot := range row {
+				if dot == '#' {
+					fillRect(img, x+gx*GLYPH_SCALE, y+gy*GLYPH_SCALE, GLYPH_SCALE, GLYPH_SCALE, rgb)
+				}
+			}
+		}
+		x += GLYPH_ADVANCE
+	}
+}
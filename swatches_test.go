@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestStripWidths(t *testing.T) {
+	colors := RGBColorPairSlice{
+		{rgb: RGB{255, 0, 0}, pixels: 600},
+		{rgb: RGB{0, 255, 0}, pixels: 300},
+		{rgb: RGB{0, 0, 255}, pixels: 99},
+		{rgb: RGB{9, 9, 9}, pixels: 1},
+	}
+	info := ImageInfo{Width: 100, Height: 10}
+
+	s, err := layoutSwatches(colors, info, "strip", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{720, 360, 119, 1}
+	if len(s.Rects) != len(expected) {
+		t.Fatalf("expected %d swatches, but got %d", len(expected), len(s.Rects))
+	}
+	x := 0
+	for i, r := range s.Rects {
+		if r.x != x || r.w != expected[i] {
+			t.Errorf("swatch %d: expected x %d and width %d, but got %d and %d", i, x, expected[i], r.x, r.w)
+		}
+		x += r.w
+	}
+
+	if got := strings.Join(s.Rects[0].labels, " "); got != "#FF0000 60.00%" {
+		t.Errorf("expected the labels #FF0000 60.00%%, but got %q", got)
+	}
+	if s.Rects[3].labels != nil {
+		t.Errorf("expected no labels on a swatch of 1 pixel, but got %v", s.Rects[3].labels)
+	}
+
+	if _, err := layoutSwatches(colors, info, "circle", false); err == nil {
+		t.Error("expected an error for an unknown layout")
+	}
+}
+
+func TestSwatchesPNG(t *testing.T) {
+	colors := RGBColorPairSlice{
+		{rgb: RGB{10, 20, 30}, pixels: 1},
+		{rgb: RGB{200, 100, 50}, pixels: 1},
+		{rgb: RGB{0, 128, 255}, pixels: 1},
+	}
+
+	s, err := layoutSwatches(colors, ImageInfo{Width: 3, Height: 1}, "grid", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeSwatchesPNG(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 3*GRID_CELL || b.Dy() != GRID_CELL {
+		t.Fatalf("expected %dx%d, but got %v", 3*GRID_CELL, GRID_CELL, b)
+	}
+	for i, cc := range colors {
+		if got := fromColor(img.At(i*GRID_CELL+GRID_CELL/2, GRID_CELL/2)); got != cc.rgb {
+			t.Errorf("swatch %d: expected %v, but got %v", i, cc.rgb, got)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ColorMode is how many colors the terminal can show.
+type ColorMode int
+
+const (
+	COLOR_NONE ColorMode = iota
+	COLOR_16
+	COLOR_256
+	COLOR_TRUE
+)
+
+var COLOR_WHEN = []string{"auto", "always", "never"}
+
+// colorMode decides the escape sequences colored emits. It is set once from
+// the --color flag and the environment.
+var colorMode = COLOR_TRUE
+
+// detectColorMode picks the color mode for --color=when, with tty telling
+// if stdout is a terminal. A non-empty NO_COLOR leaves out colors unless
+// they are forced with always. auto leaves them out if stdout is not a
+// terminal as well, always falls back to truecolor if the terminal is
+// unknown.
+func detectColorMode(when string, tty bool) (ColorMode, error) {
+	if !slices.Contains(COLOR_WHEN, when) {
+		return COLOR_NONE, fmt.Errorf("unknown --color %q, use one of %s", when, strings.Join(COLOR_WHEN, ", "))
+	}
+	if when == "always" {
+		if mode := terminalColors(); mode != COLOR_NONE {
+			return mode, nil
+		}
+		return COLOR_TRUE, nil
+	}
+	if when == "never" || os.Getenv("NO_COLOR") != "" || !tty {
+		return COLOR_NONE, nil
+	}
+	return terminalColors(), nil
+}
+
+// DEFAULT_COLUMNS is the width assumed if neither the terminal nor COLUMNS
+// tell.
+const DEFAULT_COLUMNS = 80
+
+// terminalWidth is the number of columns of the terminal on stdout, of
+// COLUMNS if stdout is not a terminal, or DEFAULT_COLUMNS.
+func terminalWidth() int {
+	if cols, _, err := terminalSize(os.Stdout); err == nil && cols > 0 {
+		return cols
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return DEFAULT_COLUMNS
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalColors reads the color support from COLORTERM, TERM and the
+// terminfo entry of TERM, in that order.
+func terminalColors() ColorMode {
+	colorterm := os.Getenv("COLORTERM")
+	if colorterm == "truecolor" || colorterm == "24bit" {
+		return COLOR_TRUE
+	}
+
+	term := os.Getenv("TERM")
+	switch {
+	case term == "" || term == "dumb":
+		return COLOR_NONE
+	case strings.HasSuffix(term, "-direct"):
+		return COLOR_TRUE
+	case strings.Contains(term, "256color"):
+		return COLOR_256
+	}
+
+	colors, err := terminfoColors(term)
+	switch {
+	case err != nil:
+		// most terminals that set TERM at all know the basic colors
+		return COLOR_16
+	case colors >= 1<<24:
+		return COLOR_TRUE
+	case colors >= 256:
+		return COLOR_256
+	case colors >= 8:
+		return COLOR_16
+	default:
+		return COLOR_NONE
+	}
+}
+
+// terminfoDirs are searched for compiled terminfo entries like ncurses does.
+func terminfoDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, path.Join(home, ".terminfo"))
+	}
+	for _, dir := range strings.Split(os.Getenv("TERMINFO_DIRS"), ":") {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo", "/usr/lib/terminfo")
+}
+
+// Magic numbers of compiled terminfo entries with 16 and 32 bit numbers.
+const (
+	TERMINFO_MAGIC          = 0o432
+	TERMINFO_MAGIC_EXTENDED = 0o1036
+)
+
+// TERMINFO_COLORS is the index of max_colors in the numbers section.
+const TERMINFO_COLORS = 13
+
+// terminfoColors reads max_colors from the compiled terminfo entry of term.
+// Entries live in a directory named after the first letter of the name or,
+// on macOS, its hex code.
+func terminfoColors(term string) (int, error) {
+	var (
+		data []byte
+		err  error
+	)
+	for _, dir := range terminfoDirs() {
+		for _, sub := range []string{term[:1], fmt.Sprintf("%x", term[0])} {
+			data, err = os.ReadFile(path.Join(dir, sub, term))
+			if err == nil {
+				return parseTerminfoColors(data)
+			}
+		}
+	}
+	return 0, fmt.Errorf("no terminfo entry for %s", term)
+}
+
+// parseTerminfoColors reads max_colors from the numbers section, which
+// follows the header, the names and the booleans padded to an even offset.
+func parseTerminfoColors(data []byte) (int, error) {
+	if len(data) < 12 {
+		return 0, fmt.Errorf("terminfo entry of %d bytes is too short", len(data))
+	}
+
+	var header [6]int
+	for i := range header {
+		header[i] = int(int16(binary.LittleEndian.Uint16(data[2*i:])))
+	}
+	magic, namesSize, boolCount, numCount := header[0], header[1], header[2], header[3]
+	if namesSize < 0 || boolCount < 0 || numCount < 0 {
+		return 0, fmt.Errorf("malformed terminfo entry with sizes %d, %d and %d", namesSize, boolCount, numCount)
+	}
+
+	numSize := 2
+	switch magic {
+	case TERMINFO_MAGIC:
+	case TERMINFO_MAGIC_EXTENDED:
+		numSize = 4
+	default:
+		return 0, fmt.Errorf("unknown terminfo magic %o", magic)
+	}
+
+	if numCount <= TERMINFO_COLORS {
+		return -1, nil
+	}
+	offset := 12 + namesSize + boolCount
+	offset += offset % 2
+	offset += TERMINFO_COLORS * numSize
+	if offset+numSize > len(data) {
+		return 0, fmt.Errorf("terminfo entry is truncated")
+	}
+
+	if numSize == 2 {
+		return int(int16(binary.LittleEndian.Uint16(data[offset:]))), nil
+	}
+	return int(int32(binary.LittleEndian.Uint32(data[offset:]))), nil
+}
+
+// XTERM_LEVELS are the channel values of the 6x6x6 color cube of xterm-256,
+// which starts at index 16, followed by 24 grays from 232 on.
+var XTERM_LEVELS = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// ANSI_16 are the default xterm values of the 8 normal and 8 bright colors.
+var ANSI_16 = [16]RGB{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// xterm256 returns the nearest entry of the color cube or the gray ramp.
+// The first 16 entries are left out, terminal themes change them.
+func xterm256(rgb RGB) int {
+	best, bestDist := 0, -1.0
+	for i := 16; i < 256; i++ {
+		var c RGB
+		if i < 232 {
+			n := i - 16
+			c = RGB{XTERM_LEVELS[n/36], XTERM_LEVELS[n/6%6], XTERM_LEVELS[n%6]}
+		} else {
+			gray := uint8(8 + 10*(i-232))
+			c = RGB{gray, gray, gray}
+		}
+		if d := dist(rgb, c); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// ansi16 returns the nearest of the 16 basic colors.
+func ansi16(rgb RGB) int {
+	best, bestDist := 0, -1.0
+	for i, c := range ANSI_16 {
+		if d := dist(rgb, c); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// swatch wraps text in the foreground color rgb, if colors are enabled.
+func swatch(rgb RGB, text string) string {
+	if colorMode == COLOR_NONE {
+		return text
+	}
+	return colored(rgb) + text + Reset
+}
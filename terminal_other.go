@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupportedTerminal = errors.New("terminal control is not supported on this platform")
+
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errUnsupportedTerminal
+}
+
+func makeRaw(f *os.File) (restore func() error, err error) {
+	return nil, errUnsupportedTerminal
+}
+
+func notifyResize(c chan<- os.Signal) {}
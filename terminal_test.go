@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// terminfoEntry compiles an entry with max_colors set to colors, with 32 bit
+// numbers if extended. The name is of odd length, so the numbers need the
+// padding byte.
+func terminfoEntry(colors int, extended bool) []byte {
+	names := "test|terminal for tests\x00"
+	bools := []byte{1, 0, 1}
+	numCount := TERMINFO_COLORS + 2
+
+	magic := TERMINFO_MAGIC
+	if extended {
+		magic = TERMINFO_MAGIC_EXTENDED
+	}
+	var data []byte
+	for _, v := range []int{magic, len(names), len(bools), numCount, 0, 0} {
+		data = binary.LittleEndian.AppendUint16(data, uint16(v))
+	}
+	data = append(data, names...)
+	data = append(data, bools...)
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	for i := range numCount {
+		v := -1
+		if i == TERMINFO_COLORS {
+			v = colors
+		}
+		if extended {
+			data = binary.LittleEndian.AppendUint32(data, uint32(v))
+		} else {
+			data = binary.LittleEndian.AppendUint16(data, uint16(v))
+		}
+	}
+	return data
+}
+
+// withHeader returns a copy of data with the i-th header field set to v.
+func withHeader(data []byte, i int, v int16) []byte {
+	data = slices.Clone(data)
+	binary.LittleEndian.PutUint16(data[2*i:], uint16(v))
+	return data
+}
+
+func TestParseTerminfoColors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected int
+		err      bool
+	}{
+		{"legacy", terminfoEntry(8, false), 8, false},
+		{"legacy 256", terminfoEntry(256, false), 256, false},
+		{"extended", terminfoEntry(1<<24, true), 1 << 24, false},
+		{"absent", terminfoEntry(-1, false), -1, false},
+		{"short", []byte{0x1a, 0x01}, 0, true},
+		{"magic", append([]byte{0x00, 0x00}, terminfoEntry(8, false)[2:]...), 0, true},
+		{"truncated", terminfoEntry(8, false)[:40], 0, true},
+		{"negative names", withHeader(terminfoEntry(8, false), 1, -100), 0, true},
+		{"negative booleans", withHeader(terminfoEntry(8, false), 2, -30), 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTerminfoColors(tt.data)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %t, but got %v", tt.name, tt.err, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %d colors, but got %d", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestXterm256(t *testing.T) {
+	tests := []struct {
+		rgb      RGB
+		expected int
+	}{
+		{RGB{0, 0, 0}, 16},
+		{RGB{255, 255, 255}, 231},
+		{RGB{255, 0, 0}, 196},
+		{RGB{95, 135, 175}, 67},
+		{RGB{128, 128, 128}, 244},
+		{RGB{8, 8, 8}, 232},
+	}
+	for _, tt := range tests {
+		if got := xterm256(tt.rgb); got != tt.expected {
+			t.Errorf("%v: expected %d, but got %d", tt.rgb, tt.expected, got)
+		}
+	}
+}
+
+func TestAnsi16(t *testing.T) {
+	tests := []struct {
+		rgb      RGB
+		expected int
+	}{
+		{RGB{0, 0, 0}, 0},
+		{RGB{200, 10, 10}, 1},
+		{RGB{255, 20, 20}, 9},
+		{RGB{130, 130, 130}, 8},
+		{RGB{250, 250, 250}, 15},
+	}
+	for _, tt := range tests {
+		if got := ansi16(tt.rgb); got != tt.expected {
+			t.Errorf("%v: expected %d, but got %d", tt.rgb, tt.expected, got)
+		}
+	}
+}
+
+func TestDetectColorMode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "t"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "t", "test-term"), terminfoEntry(256, false), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TERMINFO", dir)
+	t.Setenv("TERMINFO_DIRS", "")
+	t.Setenv("HOME", dir)
+
+	tests := []struct {
+		when      string
+		tty       bool
+		noColor   string
+		term      string
+		colorterm string
+		expected  ColorMode
+	}{
+		{"auto", true, "", "xterm-256color", "", COLOR_256},
+		{"auto", true, "", "xterm", "truecolor", COLOR_TRUE},
+		{"auto", true, "", "test-term", "", COLOR_256},
+		{"auto", true, "", "dumb", "", COLOR_NONE},
+		{"auto", false, "", "xterm-256color", "", COLOR_NONE},
+		{"auto", true, "1", "xterm-256color", "", COLOR_NONE},
+		{"never", true, "", "xterm-256color", "", COLOR_NONE},
+		{"always", false, "", "xterm-256color", "", COLOR_256},
+		{"always", false, "1", "xterm-256color", "", COLOR_256},
+		{"always", false, "", "dumb", "", COLOR_TRUE},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("NO_COLOR", tt.noColor)
+		t.Setenv("TERM", tt.term)
+		t.Setenv("COLORTERM", tt.colorterm)
+		got, err := detectColorMode(tt.when, tt.tty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.expected {
+			t.Errorf("--color %s, tty %t, NO_COLOR %q, TERM %s, COLORTERM %q: expected %d, but got %d",
+				tt.when, tt.tty, tt.noColor, tt.term, tt.colorterm, tt.expected, got)
+		}
+	}
+
+	if _, err := detectColorMode("sometimes", true); err == nil {
+		t.Error("expected an error for an unknown --color")
+	}
+}
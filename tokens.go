@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Design tokens are the JSON format of the Design Tokens Community Group: a
+// tree of groups whose leaves are tokens with a $value. The $type can be set
+// on the token or on any group above it.
+// https://tr.designtokens.org/format/
+
+const TOKENS_EXT = ".tokens.json"
+
+// Token is a color design token, named by the path of groups leading to it.
+type Token struct {
+	Name string
+	rgb  RGB
+}
+
+// writeTokens writes every color as a token of type color.
+func writeTokens(w io.Writer, e Export) error {
+	names, err := variableNames(e)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for i, cc := range e.Colors {
+		key, _ := json.Marshal(names[i])
+		fmt.Fprintf(&b, "  %s: {\n    \"$type\": \"color\",\n    \"$value\": \"%s\"\n  }", key, lowerHex(cc.rgb))
+		if i < len(e.Colors)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// loadTokens reads the color tokens of a design tokens file, sorted by name.
+// References to other tokens like "{brand.primary}" are resolved.
+func loadTokens(filepath string) ([]Token, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := parseTokens(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading tokens %s: %v", filepath, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no color tokens in %s", filepath)
+	}
+	return tokens, nil
+}
+
+// parseTokens reads the color tokens of a design tokens file, sorted by
+// name. A token without a $type of its own or of a group above it has the
+// type of the token it references.
+func parseTokens(data []byte) ([]Token, error) {
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	types := map[string]string{}
+	collectTokens(root, nil, "", values, types)
+
+	var tokens []Token
+	for name, value := range values {
+		tokenType, err := resolveType(name, values, types, 0)
+		if err != nil {
+			return nil, fmt.Errorf("token %s: %v", name, err)
+		}
+		if tokenType != "color" {
+			continue
+		}
+		rgb, err := resolveToken(value, values, 0)
+		if err != nil {
+			return nil, fmt.Errorf("token %s: %v", name, err)
+		}
+		tokens = append(tokens, Token{name, rgb})
+	}
+	slices.SortFunc(tokens, func(a, b Token) int { return strings.Compare(a.Name, b.Name) })
+	return tokens, nil
+}
+
+// collectTokens walks the groups and records the $value and the inherited
+// $type of every token.
+func collectTokens(group map[string]any, path []string, inherited string, values map[string]any, types map[string]string) {
+	if t, ok := group["$type"].(string); ok {
+		inherited = t
+	}
+	if value, ok := group["$value"]; ok {
+		name := strings.Join(path, ".")
+		values[name] = value
+		types[name] = inherited
+		return
+	}
+
+	for key, child := range group {
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		if child, ok := child.(map[string]any); ok {
+			collectTokens(child, append(slices.Clone(path), key), inherited, values, types)
+		}
+	}
+}
+
+// reference returns the name of the token a $value like "{brand.primary}"
+// refers to.
+func reference(value any) (string, bool) {
+	v, ok := value.(string)
+	if !ok || !strings.HasPrefix(v, "{") || !strings.HasSuffix(v, "}") {
+		return "", false
+	}
+	return v[1 : len(v)-1], true
+}
+
+// resolveType returns the $type of the token name, following references
+// for tokens without one.
+func resolveType(name string, values map[string]any, types map[string]string, depth int) (string, error) {
+	if t := types[name]; t != "" {
+		return t, nil
+	}
+	if depth > len(values) {
+		return "", fmt.Errorf("circular reference")
+	}
+	ref, ok := reference(values[name])
+	if !ok {
+		return "", nil
+	}
+	if _, ok := values[ref]; !ok {
+		return "", fmt.Errorf("unknown reference {%s}", ref)
+	}
+	return resolveType(ref, values, types, depth+1)
+}
+
+// resolveToken turns a $value into a color. Values are either a hex string,
+// a reference to another token or an object with sRGB components.
+func resolveToken(value any, values map[string]any, depth int) (RGB, error) {
+	if depth > len(values) {
+		return RGB{}, fmt.Errorf("circular reference")
+	}
+
+	if ref, ok := reference(value); ok {
+		target, ok := values[ref]
+		if !ok {
+			return RGB{}, fmt.Errorf("unknown reference {%s}", ref)
+		}
+		return resolveToken(target, values, depth+1)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return parseHexColor(v)
+	case map[string]any:
+		if space, _ := v["colorSpace"].(string); space == "srgb" {
+			if components, ok := v["components"].([]any); ok && len(components) == 3 {
+				var c [3]uint8
+				for i, component := range components {
+					f, ok := component.(float64)
+					if !ok {
+						return RGB{}, fmt.Errorf("component %v is not a number", component)
+					}
+					c[i] = uint8(math.Round(min(max(f, 0), 1) * 255))
+				}
+				return RGB{c[0], c[1], c[2]}, nil
+			}
+		}
+		if hex, ok := v["hex"].(string); ok {
+			return parseHexColor(hex)
+		}
+	}
+
+	return RGB{}, fmt.Errorf("unsupported color value %v", value)
+}
+
+// nearestToken returns the token closest to rgb.
+func nearestToken(rgb RGB, tokens []Token) (Token, float64) {
+	best, bestDist := Token{}, math.Inf(1)
+	for _, t := range tokens {
+		if d := dist(rgb, t.rgb); d < bestDist {
+			best, bestDist = t, d
+		}
+	}
+	return best, bestDist
+}
+
+// unmatchedTokens returns the tokens without a color within maxDist.
+func unmatchedTokens(tokens []Token, colors RGBColorPairSlice, maxDist float64) []Token {
+	var unmatched []Token
+	for _, t := range tokens {
+		matched := slices.ContainsFunc(colors, func(cc RGBCountPair) bool {
+			return dist(cc.rgb, t.rgb) <= maxDist
+		})
+		if !matched {
+			unmatched = append(unmatched, t)
+		}
+	}
+	return unmatched
+}
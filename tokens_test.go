@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTokens(t *testing.T) {
+	input := `{
+  "brand": {
+    "$type": "color",
+    "primary": {"$value": "#3a7bd5"},
+    "light": {"$value": {"colorSpace": "srgb", "components": [1, 1, 0.5]}},
+    "nested": {
+      "accent": {"$value": "{brand.primary}"}
+    }
+  },
+  "button": {
+    "background": {"$value": "{brand.light}"},
+    "radius": {"$type": "dimension", "$value": "4px"}
+  },
+  "spacing": {"$value": "{button.radius}"}
+}`
+
+	tokens, err := parseTokens([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// button.background has its type through the reference, spacing is a
+	// dimension the same way and left out
+	expected := []Token{
+		{"brand.light", RGB{255, 255, 128}},
+		{"brand.nested.accent", RGB{0x3a, 0x7b, 0xd5}},
+		{"brand.primary", RGB{0x3a, 0x7b, 0xd5}},
+		{"button.background", RGB{255, 255, 128}},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, but got %v", len(expected), tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("expected %v, but got %v", expected[i], tokens[i])
+		}
+	}
+}
+
+func TestParseTokensErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": {"$type": "color", "$value": "{b}"}, "b": {"$type": "color", "$value": "{a}"}}`, "circular reference"},
+		{`{"a": {"$value": "{b}"}, "b": {"$value": "{a}"}}`, "circular reference"},
+		{`{"a": {"$value": "{missing}"}}`, "unknown reference {missing}"},
+		{`{"$type": "color", "a": {"$value": "blue"}}`, "token a"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseTokens([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected an error with %q, but got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestUnmatchedTokens(t *testing.T) {
+	tokens := []Token{{"red", RGB{255, 0, 0}}, {"blue", RGB{0, 0, 255}}}
+	colors := RGBColorPairSlice{{rgb: RGB{250, 5, 0}}}
+
+	if got, d := nearestToken(RGB{0, 0, 200}, tokens); got.Name != "blue" || d != 55 {
+		t.Errorf("expected blue at distance 55, but got %s at %.1f", got.Name, d)
+	}
+	unmatched := unmatchedTokens(tokens, colors, 10)
+	if len(unmatched) != 1 || unmatched[0].Name != "blue" {
+		t.Errorf("expected blue to be unmatched, but got %v", unmatched)
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Escape sequences of the interactive mode. The alternate screen keeps the
+// scrollback intact, and without auto wrap long lines are cut off instead of
+// breaking the layout.
+const (
+	ALT_SCREEN_ON  = "\033[?1049h\033[?25l\033[?7l"
+	ALT_SCREEN_OFF = "\033[?7h\033[?25h\033[?1049l"
+	CURSOR_HOME    = "\033[H"
+	CLEAR_LINE     = "\033[K"
+	CLEAR_BELOW    = "\033[J"
+	REVERSE        = "\033[7m"
+)
+
+// How much the proximity keys change the proximity.
+const (
+	PROXIMITY_STEP = 1.0
+	PROXIMITY_JUMP = 5.0
+)
+
+// Rows above and below the list of colors.
+const (
+	TUI_HEADER_ROWS = 2
+	TUI_FOOTER_ROWS = 2
+)
+
+// TUI_HELP lists the keys of the interactive mode.
+const TUI_HELP = "↑↓/jk move  s sort  +/- proximity ±1  ]/[ ±5  n/N notation  p preview  c copy  q quit"
+
+// key is a key press, the character itself or the name of a special key.
+type key string
+
+const (
+	KEY_UP        key = "up"
+	KEY_DOWN      key = "down"
+	KEY_PAGE_UP   key = "pgup"
+	KEY_PAGE_DOWN key = "pgdown"
+	KEY_HOME      key = "home"
+	KEY_END       key = "end"
+	KEY_ENTER     key = "enter"
+	KEY_ESCAPE    key = "esc"
+	KEY_CTRL_C    key = "ctrl-c"
+)
+
+// ESCAPE_KEYS are the sequences xterm and most terminals send for special
+// keys, in normal and application cursor mode.
+var ESCAPE_KEYS = map[string]key{
+	"\033[A": KEY_UP, "\033OA": KEY_UP,
+	"\033[B": KEY_DOWN, "\033OB": KEY_DOWN,
+	"\033[5~": KEY_PAGE_UP, "\033[6~": KEY_PAGE_DOWN,
+	"\033[H": KEY_HOME, "\033OH": KEY_HOME, "\033[1~": KEY_HOME,
+	"\033[F": KEY_END, "\033OF": KEY_END, "\033[4~": KEY_END,
+}
+
+// ESCAPE_TIMEOUT is how long an escape sequence cut off at the end of a
+// read waits for its remaining bytes, which arrive later over slow links
+// such as ssh. A lone ESC is the escape key once it has passed.
+const ESCAPE_TIMEOUT = 250 * time.Millisecond
+
+// parseKeys splits what was read from the terminal into key presses.
+// Unknown escape sequences are dropped. An escape sequence cut off at the
+// end of b is returned as rest, to be completed by the next read or, if
+// none follows in time, passed to flushKeys.
+func parseKeys(b []byte) (keys []key, rest []byte) {
+	for len(b) > 0 {
+		switch b[0] {
+		case '\033':
+			if len(b) == 1 {
+				return keys, b
+			}
+			// a sequence ends with the first letter or ~ after the prefix
+			end := 2
+			for end < len(b) && !(b[end] >= 'A' && b[end] <= 'Z' || b[end] >= 'a' && b[end] <= 'z' || b[end] == '~') {
+				end++
+			}
+			if end == len(b) && (b[1] == '[' || b[1] == 'O') {
+				return keys, b
+			}
+			end = min(end+1, len(b))
+			if k, ok := ESCAPE_KEYS[string(b[:end])]; ok {
+				keys = append(keys, k)
+			}
+			b = b[end:]
+			continue
+		case '\r', '\n':
+			keys = append(keys, KEY_ENTER)
+		case 3:
+			keys = append(keys, KEY_CTRL_C)
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, key(string(r)))
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys, nil
+}
+
+// flushKeys handles the rest of parseKeys when nothing followed it: a lone
+// ESC is the escape key, an unfinished sequence is dropped.
+func flushKeys(rest []byte) []key {
+	if string(rest) == "\033" {
+		return []key{KEY_ESCAPE}
+	}
+	return nil
+}
+
+// tui is the state of the interactive mode. The colors are grouped again
+// whenever the proximity changes.
+type tui struct {
+	colors     map[RGB]int
+	info       ImageInfo
+	names      []NamedColor
+	proximity  float64
+	sortBy     string
+	minPercent float64
+	limit      int
+	notation   int
+
+	grouped  RGBColorPairSlice
+	groups   map[RGB]int
+	selected int
+	offset   int
+	status   string
+
+	// bitmap is shown instead of the list if preview is on, with the pixels
+	// of the selected color outlined.
+	bitmap  Bitmap
+	preview bool
+
+	clipboard Clipboard
+}
+
+func newTUI(colors map[RGB]int, info ImageInfo, names []NamedColor, proximity float64, sortBy, notation string, minPercent float64, limit int) *tui {
+	t := &tui{
+		colors:     colors,
+		info:       info,
+		names:      names,
+		proximity:  proximity,
+		sortBy:     sortBy,
+		minPercent: minPercent,
+		limit:      limit,
+		notation:   max(slices.Index(NOTATION_NAMES, notation), 0),
+	}
+	if t.sortBy == "" {
+		t.sortBy = "count"
+	}
+	t.regroup()
+	return t
+}
+
+// regroup groups, sorts, filters and limits the colors again.
+func (t *tui) regroup() {
+	grouped, groups := groupColors(t.colors, t.proximity)
+	t.groups = groups
+	grouped = sort(grouped, t.sortBy)
+	grouped = filterPercent(grouped, t.info, t.minPercent)
+	t.grouped = limitSlice(grouped, t.limit)
+	t.selected = min(t.selected, max(len(t.grouped)-1, 0))
+}
+
+func (t *tui) notationName() string {
+	return NOTATION_NAMES[t.notation]
+}
+
+// handle applies a key press to the state and reports if it quits.
+func (t *tui) handle(k key, pageSize int) bool {
+	t.status = ""
+	switch k {
+	case "q", KEY_ESCAPE, KEY_CTRL_C:
+		return true
+	case KEY_UP, "k":
+		t.selected--
+	case KEY_DOWN, "j":
+		t.selected++
+	case KEY_PAGE_UP:
+		t.selected -= pageSize
+	case KEY_PAGE_DOWN:
+		t.selected += pageSize
+	case KEY_HOME, "g":
+		t.selected = 0
+	case KEY_END, "G":
+		t.selected = len(t.grouped) - 1
+	case "s":
+		t.sortBy = SORT_BY[(slices.Index(SORT_BY, t.sortBy)+1)%len(SORT_BY)]
+		t.regroup()
+	case "+", "=":
+		t.setProximity(t.proximity + PROXIMITY_STEP)
+	case "-":
+		t.setProximity(t.proximity - PROXIMITY_STEP)
+	case "]":
+		t.setProximity(t.proximity + PROXIMITY_JUMP)
+	case "[":
+		t.setProximity(t.proximity - PROXIMITY_JUMP)
+	case "n":
+		t.notation = (t.notation + 1) % len(NOTATION_NAMES)
+	case "N":
+		t.notation = (t.notation + len(NOTATION_NAMES) - 1) % len(NOTATION_NAMES)
+	case "p":
+		t.preview = !t.preview
+	case "c", "y", KEY_ENTER:
+		t.copySelected()
+	}
+	t.selected = min(max(t.selected, 0), max(len(t.grouped)-1, 0))
+	return false
+}
+
+func (t *tui) setProximity(proximity float64) {
+	t.proximity = max(proximity, 0)
+	t.regroup()
+}
+
+// selectedText is the selected color in the current notation.
+func (t *tui) selectedText() (string, bool) {
+	if len(t.grouped) == 0 {
+		return "", false
+	}
+	return NOTATIONS[t.notationName()].format(t.grouped[t.selected].rgb), true
+}
+
+func (t *tui) copySelected() {
+	text, ok := t.selectedText()
+	if !ok {
+		return
+	}
+	if err := t.clipboard.Copy(text); err != nil {
+		t.status = fmt.Sprintf("copying failed: %v", err)
+		return
+	}
+	t.status = "copied " + text
+}
+
+// render draws the whole screen of width columns and height rows.
+func (t *tui) render(width, height int) string {
+	rows := max(height-TUI_HEADER_ROWS-TUI_FOOTER_ROWS, 1)
+	if t.selected < t.offset {
+		t.offset = t.selected
+	}
+	if t.selected >= t.offset+rows {
+		t.offset = t.selected - rows + 1
+	}
+
+	var b strings.Builder
+	b.WriteString(CURSOR_HOME)
+	line := func(s string) {
+		b.WriteString(s + CLEAR_LINE + "\r\n")
+	}
+
+	line(fmt.Sprintf("%s  %d×%d  %d of %d colors  proximity %.1f  sort %s  notation %s",
+		t.info.Path, t.info.Width, t.info.Height, len(t.grouped), t.info.UniqueColors, t.proximity, t.sortBy, t.notationName()))
+	line("")
+
+	barWidth := min(max(width/4, BAR_MIN_WIDTH), BAR_MAX_WIDTH)
+	if t.preview {
+		// the preview of the selected color takes the place of the list
+		var selected *RGBCountPair
+		if len(t.grouped) > 0 {
+			selected = &t.grouped[t.selected]
+		}
+		preview := renderPreview(t.bitmap, width, max(rows-2, 1), selected, t.groups)
+		for _, l := range preview {
+			line(l)
+		}
+		for range rows - 1 - len(preview) {
+			line("")
+		}
+		if len(t.grouped) > 0 {
+			line(t.row(t.selected, barWidth))
+		} else {
+			line("")
+		}
+	} else {
+		for i := t.offset; i < min(t.offset+rows, len(t.grouped)); i++ {
+			line(t.row(i, barWidth))
+		}
+		for i := len(t.grouped) - t.offset; i < rows; i++ {
+			line("")
+		}
+	}
+
+	line(t.status)
+	b.WriteString(TUI_HELP + CLEAR_LINE + CLEAR_BELOW)
+	return b.String()
+}
+
+// row is the line of the i-th color.
+func (t *tui) row(i, barWidth int) string {
+	cc := t.grouped[i]
+	share := percent(cc.pixels, t.info)
+	text := fmt.Sprintf(" %-28s %6.2f%% ", NOTATIONS[t.notationName()].format(cc.rgb), share)
+	if i == t.selected {
+		text = REVERSE + ">" + text[1:] + Reset
+	}
+	blocks := bar(share, barWidth)
+	row := swatch(cc.rgb, strings.Repeat(FullBlock, 4)) + text + swatch(cc.rgb, blocks)
+	if t.names != nil {
+		n, d := nearestName(cc.rgb, t.names)
+		row += strings.Repeat(" ", barWidth-utf8.RuneCountInString(blocks)) + fmt.Sprintf(" ≈ %s (distance %.1f)", n.Name, d)
+	}
+	return row
+}
+
+// runInteractive shows the colors full-screen until q is pressed. It needs
+// stdin and stdout to be a terminal.
+func runInteractive(t *tui, in, out *os.File) error {
+	if !isTerminal(in) || !isTerminal(out) {
+		return fmt.Errorf("--interactive needs a terminal")
+	}
+	restore, err := makeRaw(in)
+	if err != nil {
+		return fmt.Errorf("entering raw mode: %v", err)
+	}
+	defer restore()
+
+	if t.clipboard == nil {
+		t.clipboard = osc52Clipboard{out, os.Getenv("TMUX") != ""}
+	}
+
+	io.WriteString(out, ALT_SCREEN_ON)
+	defer io.WriteString(out, ALT_SCREEN_OFF)
+
+	input := make(chan []byte)
+	done := make(chan struct{})
+	defer close(done)
+	go readInput(in, input, done)
+
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+	defer signal.Stop(resize)
+
+	var (
+		pending []byte
+		escape  <-chan time.Time
+	)
+	for {
+		width, height, err := terminalSize(out)
+		if err != nil {
+			width, height = DEFAULT_COLUMNS, 24
+		}
+		if _, err := io.WriteString(out, t.render(width, height)); err != nil {
+			return err
+		}
+
+		var pressed []key
+		select {
+		case data, ok := <-input:
+			if !ok {
+				return nil
+			}
+			pressed, pending = parseKeys(append(pending, data...))
+			escape = nil
+			if len(pending) > 0 {
+				escape = time.After(ESCAPE_TIMEOUT)
+			}
+		case <-escape:
+			pressed, pending, escape = flushKeys(pending), nil, nil
+		case <-resize:
+		}
+
+		for _, k := range pressed {
+			if t.handle(k, max(height-TUI_HEADER_ROWS-TUI_FOOTER_ROWS, 1)) {
+				return nil
+			}
+		}
+	}
+}
+
+// readInput sends what is read from in until a read fails or done is
+// closed. A blocking read of the terminal cannot be interrupted, so after
+// done is closed the goroutine still waits in its last read and returns
+// once that ends instead of blocking on input. Until then it lives on, but
+// the program exits right after the interactive mode.
+func readInput(in *os.File, input chan<- []byte, done <-chan struct{}) {
+	defer close(input)
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		select {
+		case input <- slices.Clone(buf[:n]):
+		case <-done:
+			return
+		}
+	}
+}
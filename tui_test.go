@@ -0,0 +1,161 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []key
+		rest     string
+	}{
+		{"jk", []key{"j", "k"}, ""},
+		{"\033[A\033[B", []key{KEY_UP, KEY_DOWN}, ""},
+		{"\033OA", []key{KEY_UP}, ""},
+		{"\033[5~x\033[6~", []key{KEY_PAGE_UP, "x", KEY_PAGE_DOWN}, ""},
+		{"\033[1;5C+", []key{"+"}, ""},
+		{"\r\x03", []key{KEY_ENTER, KEY_CTRL_C}, ""},
+		{"≈", []key{"≈"}, ""},
+		// cut off sequences wait for the next read
+		{"\033", nil, "\033"},
+		{"j\033[", []key{"j"}, "\033["},
+		{"\033O", nil, "\033O"},
+		{"\033[5", nil, "\033[5"},
+	}
+
+	for _, tt := range tests {
+		got, rest := parseKeys([]byte(tt.input))
+		if !slices.Equal(got, tt.expected) || string(rest) != tt.rest {
+			t.Errorf("%q: expected %v and %q left, but got %v and %q", tt.input, tt.expected, tt.rest, got, rest)
+		}
+	}
+}
+
+// an arrow key split over two reads is not taken for ESC, which quits
+func TestParseKeysSplit(t *testing.T) {
+	keys, rest := parseKeys([]byte("k\033"))
+	if !slices.Equal(keys, []key{"k"}) {
+		t.Fatalf("expected k, but got %v", keys)
+	}
+	keys, rest = parseKeys(append(rest, "[B"...))
+	if !slices.Equal(keys, []key{KEY_DOWN}) || len(rest) != 0 {
+		t.Errorf("expected down, but got %v and %q left", keys, rest)
+	}
+
+	if keys := flushKeys([]byte("\033")); !slices.Equal(keys, []key{KEY_ESCAPE}) {
+		t.Errorf("expected a lone ESC to be the escape key, but got %v", keys)
+	}
+	if keys := flushKeys([]byte("\033[")); len(keys) != 0 {
+		t.Errorf("expected an unfinished sequence to be dropped, but got %v", keys)
+	}
+}
+
+func testTUI() *tui {
+	colors := map[RGB]int{
+		{250, 0, 0}:   10,
+		{245, 5, 5}:   30,
+		{0, 0, 250}:   40,
+		{0, 250, 0}:   15,
+		{100, 100, 0}: 5,
+	}
+	info := ImageInfo{Path: "shot.png", Width: 10, Height: 10, UniqueColors: len(colors)}
+	return newTUI(colors, info, nil, 0, "count", "HEX", 0, 0)
+}
+
+func TestTUIRegroup(t *testing.T) {
+	tt := testTUI()
+	if len(tt.grouped) != 5 {
+		t.Fatalf("expected 5 colors without grouping, but got %d", len(tt.grouped))
+	}
+
+	tt.handle("]", 10)
+	tt.handle("]", 10)
+	if tt.proximity != 10 {
+		t.Errorf("expected proximity 10, but got %.1f", tt.proximity)
+	}
+	if len(tt.grouped) >= 5 {
+		t.Errorf("expected the two reds to be grouped, but got %d colors", len(tt.grouped))
+	}
+
+	for range 20 {
+		tt.handle("-", 10)
+	}
+	if tt.proximity != 0 || len(tt.grouped) != 5 {
+		t.Errorf("expected proximity 0 and 5 colors, but got %.1f and %d", tt.proximity, len(tt.grouped))
+	}
+}
+
+func TestTUIKeys(t *testing.T) {
+	tt := testTUI()
+	clipboard := &fakeClipboard{}
+	tt.clipboard = clipboard
+
+	tt.handle(KEY_END, 10)
+	tt.handle(KEY_DOWN, 10)
+	if tt.selected != 4 {
+		t.Errorf("expected the selection to stop at 4, but got %d", tt.selected)
+	}
+	tt.handle(KEY_PAGE_UP, 10)
+	if tt.selected != 0 {
+		t.Errorf("expected the selection to stop at 0, but got %d", tt.selected)
+	}
+
+	tt.handle("s", 10)
+	if tt.sortBy != "red" {
+		t.Errorf("expected sort red after count, but got %s", tt.sortBy)
+	}
+
+	tt.handle("n", 10)
+	tt.handle("c", 10)
+	if tt.notationName() != "hexa" || !slices.Equal(clipboard.copied, []string{"#fa0000ff"}) {
+		t.Errorf("expected #fa0000ff copied as hexa, but got %v as %s", clipboard.copied, tt.notationName())
+	}
+	if tt.status != "copied #fa0000ff" {
+		t.Errorf("expected a status of the copy, but got %q", tt.status)
+	}
+
+	if !tt.handle("q", 10) {
+		t.Error("expected q to quit")
+	}
+}
+
+func TestTUIRender(t *testing.T) {
+	tt := testTUI()
+	tt.selected = 4
+
+	screen := tt.render(80, 7)
+	lines := strings.Split(screen, "\r\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 lines, but got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], "5 of 5 colors") {
+		t.Errorf("expected the header to count the colors, but got %q", lines[0])
+	}
+	// 3 rows of colors fit, scrolled to show the selected last one
+	if tt.offset != 2 || !strings.Contains(lines[4], REVERSE+">") {
+		t.Errorf("expected the selected color in the last row, but got offset %d and %q", tt.offset, lines[4])
+	}
+}
+
+func TestTUIPreview(t *testing.T) {
+	tt := testTUI()
+	tt.bitmap = NewBitmap(4, 4)
+	tt.handle("p", 10)
+	if !tt.preview {
+		t.Fatal("expected p to turn on the preview")
+	}
+
+	lines := strings.Split(tt.render(80, 10), "\r\n")
+	if len(lines) != 10 {
+		t.Fatalf("expected 10 lines, but got %d", len(lines))
+	}
+	if n := strings.Count(lines[2], UpperHalfBlock); n != 4 {
+		t.Errorf("expected a preview 4 cells wide, but got %q", lines[2])
+	}
+	if !strings.Contains(lines[7], REVERSE+">") {
+		t.Errorf("expected the selected color below the preview, but got %q", lines[7])
+	}
+}